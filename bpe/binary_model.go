@@ -0,0 +1,251 @@
+package bpe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// Binary model format, all integers little-endian uint32:
+//
+//	magic "BPEB", version, number of merges, vocabulary size, arena length,
+//	number of characters, SentencePiece flags (0 for GPT4_SPLIT_PATTERN)
+//	chars:   rune, index per character
+//	merges:  first, second, index per merge
+//	offsets: vocabulary size + 1 arena offsets
+//	arena:   token bytes
+//
+// Every section starts 4-byte aligned, so a memory-mapped file is used in place.
+// Version 1 files end the header before the character count, version 2
+// files before the flags.
+const (
+	BINARY_MAGIC   = "BPEB"
+	BINARY_VERSION = 3
+)
+
+const binaryHeaderSize = 28
+
+// IsBinaryModel reports whether data starts like a binary model
+func IsBinaryModel(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(BINARY_MAGIC))
+}
+
+/**
+ * Write the model in the binary format
+ * 1. Header with the section sizes
+ * 2. The characters and merges, then the offsets and arena of the vocabulary
+ * Binary models always keep merges within chunks, so CrossChunks models are
+ * rejected.
+**/
+func (bpe *BPETokenizer) WriteBinary(w io.Writer) error {
+	if bpe.CrossChunks {
+		return errors.New("binary models keep merges within chunks, retrain this model or add the \"" + CHUNKED_MARKER + "\" line to accept that")
+	}
+	table := bpe.table()
+	out := bufio.NewWriter(w)
+
+	put := func(values ...uint32) {
+		var scratch [4]byte
+		for _, v := range values {
+			binary.LittleEndian.PutUint32(scratch[:], v)
+			out.Write(scratch[:])
+		}
+	}
+
+	out.WriteString(BINARY_MAGIC)
+	flags := uint32(0)
+	if bpe.SentencePiece != nil {
+		flags = bpe.SentencePiece.bits()
+	}
+	put(BINARY_VERSION, uint32(len(bpe.Merges)), uint32(table.size()), uint32(len(table.arena)), uint32(len(bpe.Chars)), flags)
+	for _, c := range bpe.Chars {
+		if c.Rune < 0 || c.Index < 0 {
+			return fmt.Errorf("character %v %d is negative", c, c.Index)
+		}
+		put(uint32(c.Rune), uint32(c.Index))
+	}
+	for _, m := range bpe.Merges {
+		if m.Pair.First < 0 || m.Pair.Second < 0 || m.Index < 0 {
+			return fmt.Errorf("merge %v %d has a negative id", m.Pair, m.Index)
+		}
+		put(uint32(m.Pair.First), uint32(m.Pair.Second), uint32(m.Index))
+	}
+	put(table.offsets...)
+	out.Write(table.arena)
+
+	return out.Flush()
+}
+
+// SaveBinaryFile writes the model to path in the binary format
+func (bpe *BPETokenizer) SaveBinaryFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := bpe.WriteBinary(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+/**
+ * Load a model in the binary format
+ * 1. Check the header and that every section fits in data
+ * 2. Read the characters and merges
+ * 3. Use the offsets and arena of data as the vocabulary without copying,
+ *    so data must not be modified while the tokenizer is in use
+**/
+func (bpe *BPETokenizer) LoadBinary(data []byte) error {
+	if !IsBinaryModel(data) || len(data) < 8 {
+		return errors.New("not a binary model")
+	}
+	header := func(i int) uint32 {
+		return binary.LittleEndian.Uint32(data[4+4*i:])
+	}
+	version := header(0)
+	if version < 1 || version > BINARY_VERSION {
+		return fmt.Errorf("unsupported binary model version %d", version)
+	}
+	headerSize := binaryHeaderSize - 4*int(BINARY_VERSION-version)
+	if len(data) < headerSize {
+		return errors.New("binary model header is truncated")
+	}
+	numMerges, size, arenaLen := uint64(header(1)), uint64(header(2)), uint64(header(3))
+	numChars, flags := uint64(0), uint32(0)
+	if version >= 2 {
+		numChars = uint64(header(4))
+	}
+	if version >= 3 {
+		flags = header(5)
+	}
+	spm, err := sentencePieceFromBits(flags)
+	if err != nil {
+		return err
+	}
+
+	charsAt := uint64(headerSize)
+	mergesAt := charsAt + 8*numChars
+	offsetsAt := mergesAt + 12*numMerges
+	arenaAt := offsetsAt + 4*(size+1)
+	if arenaAt+arenaLen != uint64(len(data)) {
+		return fmt.Errorf("binary model is %d bytes, header describes %d", len(data), arenaAt+arenaLen)
+	}
+
+	var chars []Char
+	for i := uint64(0); i < numChars; i++ {
+		at := charsAt + 8*i
+		c := Char{
+			Rune:  rune(binary.LittleEndian.Uint32(data[at:])),
+			Index: int(binary.LittleEndian.Uint32(data[at+4:])),
+		}
+		if c.Rune < 0 || c.Index >= int(size) {
+			return fmt.Errorf("character %d is outside the vocabulary of %d", i, size)
+		}
+		chars = append(chars, c)
+	}
+
+	merges := make([]Merge, numMerges)
+	for i := range merges {
+		at := mergesAt + 12*uint64(i)
+		merges[i] = Merge{
+			Pair: Pair{
+				First:  int(binary.LittleEndian.Uint32(data[at:])),
+				Second: int(binary.LittleEndian.Uint32(data[at+4:])),
+			},
+			Index: int(binary.LittleEndian.Uint32(data[at+8:])),
+		}
+		if merges[i].Index >= int(size) {
+			return fmt.Errorf("merge %d has index %d outside the vocabulary of %d", i, merges[i].Index, size)
+		}
+	}
+
+	offsets := uint32s(data[offsetsAt:arenaAt])
+	for id := 0; id < int(size); id++ {
+		if offsets[id] > offsets[id+1] {
+			return fmt.Errorf("offset of token %d is out of order", id)
+		}
+	}
+	if offsets[0] != 0 || uint64(offsets[size]) != arenaLen {
+		return errors.New("offsets do not cover the arena")
+	}
+
+	vocab := &vocabulary{arena: data[arenaAt:len(data):len(data)], offsets: offsets, chars: make(map[rune]TokenID, len(chars))}
+	for _, c := range chars {
+		if c.Index >= 0 && string(vocab.token(c.Index)) == string(c.Rune) {
+			vocab.chars[c.Rune] = TokenID(c.Index)
+		}
+	}
+
+	bpe.Chars = chars
+	bpe.Merges = merges
+	bpe.SentencePiece = spm
+	bpe.CrossChunks = false
+	bpe.vocabSize = 256 + len(chars) + len(merges)
+	bpe.vocab = vocab
+	bpe.vocabChars = len(chars)
+	bpe.vocabMerges = len(merges)
+	bpe.ranks = nil
+	bpe.mergeRanks()
+	return nil
+}
+
+// uint32s views data as little-endian uint32s without copying when the host
+// is little-endian and data is aligned, and decodes a copy otherwise
+func uint32s(data []byte) []uint32 {
+	n := len(data) / 4
+	if n == 0 {
+		return []uint32{}
+	}
+	littleEndian := binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+	if littleEndian && uintptr(unsafe.Pointer(&data[0]))%4 == 0 {
+		return unsafe.Slice((*uint32)(unsafe.Pointer(&data[0])), n)
+	}
+
+	values := make([]uint32, n)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	return values
+}
+
+/**
+ * Load a model from path, memory-mapping it read-only when it is in the
+ * binary format
+ * 1. Text models are parsed like LoadFile and need no closing
+ * 2. Binary models are mapped (or read where mmap is not available) and used
+ *    in place, the returned Closer unmaps them and the tokenizer must not be
+ *    used afterwards
+**/
+func (bpe *BPETokenizer) MapFile(path string) (io.Closer, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsBinaryModel(data) {
+		err := bpe.LoadReader(bytes.NewReader(data))
+		unmap()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return closerFunc(func() error { return nil }), nil
+	}
+
+	if err := bpe.LoadBinary(data); err != nil {
+		unmap()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return closerFunc(unmap), nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
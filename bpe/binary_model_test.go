@@ -0,0 +1,172 @@
+package bpe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func trainedBinaryModel(t *testing.T) (*BPETokenizer, []byte) {
+	t.Helper()
+	tokenizer := NewBPETokenizer()
+	tokenizer.Train("hello world hello world\nhéllo 日本語")
+
+	var buf bytes.Buffer
+	if err := tokenizer.WriteBinary(&buf); err != nil {
+		t.Fatalf("WriteBinary: %v", err)
+	}
+	return tokenizer, buf.Bytes()
+}
+
+func TestBinaryModelRoundTrip(t *testing.T) {
+	tokenizer, data := trainedBinaryModel(t)
+	text := "hello world\nhéllo 日本語 hello"
+
+	loaded := NewBPETokenizer()
+	if err := loaded.LoadBinary(data); err != nil {
+		t.Fatalf("LoadBinary: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Merges, tokenizer.Merges) {
+		t.Errorf("Merges = %v, want %v", loaded.Merges, tokenizer.Merges)
+	}
+	if loaded.VocabSize() != tokenizer.VocabSize() {
+		t.Errorf("VocabSize() = %d, want %d", loaded.VocabSize(), tokenizer.VocabSize())
+	}
+	ids := tokenizer.Encode(text)
+	if got := loaded.Encode(text); !reflect.DeepEqual(got, ids) {
+		t.Errorf("Encode() = %v, want %v", got, ids)
+	}
+	if got := loaded.Decode(ids); got != text {
+		t.Errorf("Decode() = %q, want %q", got, text)
+	}
+
+	// the vocabulary is used in place
+	arenaAt := len(data) - len(loaded.vocab.arena)
+	if &loaded.vocab.arena[0] != &data[arenaAt] {
+		t.Error("LoadBinary copied the arena")
+	}
+
+	// LoadReader recognizes both formats
+	fromReader := NewBPETokenizer()
+	if err := fromReader.LoadReader(bytes.NewReader(data)); err != nil {
+		t.Fatalf("LoadReader(binary): %v", err)
+	}
+	if got := fromReader.Encode(text); !reflect.DeepEqual(got, ids) {
+		t.Errorf("LoadReader(binary).Encode() = %v, want %v", got, ids)
+	}
+}
+
+func TestLoadBinaryOldVersions(t *testing.T) {
+	tokenizer, data := trainedBinaryModel(t)
+
+	text := "hello world héllo"
+
+	// older versions end the header early, byte models are otherwise the same
+	for version := 1; version < BINARY_VERSION; version++ {
+		old := append(bytes.Clone(data[:binaryHeaderSize-4*(BINARY_VERSION-version)]), data[binaryHeaderSize:]...)
+		binary.LittleEndian.PutUint32(old[4:], uint32(version))
+
+		loaded := NewBPETokenizer()
+		if err := loaded.LoadBinary(old); err != nil {
+			t.Fatalf("LoadBinary(v%d): %v", version, err)
+		}
+		if got, want := loaded.Encode(text), tokenizer.Encode(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("v%d: Encode() = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestLoadBinaryInvalid(t *testing.T) {
+	_, data := trainedBinaryModel(t)
+	patched := func(at int, value uint32) []byte {
+		c := bytes.Clone(data)
+		binary.LittleEndian.PutUint32(c[at:], value)
+		return c
+	}
+	offsetsAt := binaryHeaderSize + 12*int(binary.LittleEndian.Uint32(data[8:]))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"text model", []byte("104-101 256\n")},
+		{"truncated", data[:len(data)-1]},
+		{"trailing bytes", append(bytes.Clone(data), 0)},
+		{"version", patched(4, BINARY_VERSION+1)},
+		{"sentencepiece flags", patched(24, 2)},
+		{"merge index", patched(binaryHeaderSize+8, 1<<20)},
+		{"offsets out of order", patched(offsetsAt+4, 1<<20)},
+		{"arena not covered", patched(offsetsAt, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewBPETokenizer().LoadBinary(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestUint32sUnaligned(t *testing.T) {
+	data := []byte{0, 1, 0, 0, 0, 2, 0, 0, 0}
+	if got := uint32s(data[1:]); !reflect.DeepEqual(got, []uint32{1, 2}) {
+		t.Errorf("uint32s() = %v, want [1 2]", got)
+	}
+}
+
+func TestMapFile(t *testing.T) {
+	tokenizer, _ := trainedBinaryModel(t)
+	text := "hello world héllo"
+	ids := tokenizer.Encode(text)
+
+	dir := t.TempDir()
+	binaryPath := filepath.Join(dir, "vocab.bin")
+	textPath := filepath.Join(dir, "vocab.model")
+	if err := tokenizer.SaveBinaryFile(binaryPath); err != nil {
+		t.Fatalf("SaveBinaryFile: %v", err)
+	}
+	if err := tokenizer.SaveFile(textPath); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+
+	for _, path := range []string{binaryPath, textPath} {
+		mapped := NewBPETokenizer()
+		closer, err := mapped.MapFile(path)
+		if err != nil {
+			t.Fatalf("MapFile(%s): %v", path, err)
+		}
+		if got := mapped.Encode(text); !reflect.DeepEqual(got, ids) {
+			t.Errorf("MapFile(%s).Encode() = %v, want %v", path, got, ids)
+		}
+		if got := mapped.Freeze().Decode(ids); got != text {
+			t.Errorf("MapFile(%s).Freeze().Decode() = %q, want %q", path, got, text)
+		}
+		if err := closer.Close(); err != nil {
+			t.Errorf("Close(%s): %v", path, err)
+		}
+
+		loaded := NewBPETokenizer()
+		if err := loaded.LoadFile(path); err != nil {
+			t.Fatalf("LoadFile(%s): %v", path, err)
+		}
+		if got := loaded.Encode(text); !reflect.DeepEqual(got, ids) {
+			t.Errorf("LoadFile(%s).Encode() = %v, want %v", path, got, ids)
+		}
+	}
+
+	empty := filepath.Join(dir, "empty.model")
+	os.WriteFile(empty, nil, 0o644)
+	if closer, err := NewBPETokenizer().MapFile(empty); err != nil {
+		t.Errorf("MapFile(empty text model): %v", err)
+	} else {
+		closer.Close()
+	}
+	if _, err := NewBPETokenizer().MapFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -3,9 +3,12 @@ package bpe
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/dlclark/regexp2"
 )
@@ -25,58 +28,89 @@ type Merge struct {
 }
 
 const VOCAB_SIZE = 256 + 100
+const MODEL_FILE = "vocab.model"
+
+// CHUNKED_MARKER is the first line of text models whose merges stay within
+// pre-tokenizer chunks, models without it merge across chunks
+const CHUNKED_MARKER = "chunked"
+
 const GPT4_SPLIT_PATTERN = `(?i:'[sdmt]|'ll|'ve|'re)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]|\s+(?!\S)|\s+`
 
+var splitRegex = regexp2.MustCompile(GPT4_SPLIT_PATTERN, regexp2.None)
+
 type BPETokenizer struct {
-	vocab     map[string]int // {hello: 0, world: 1, ...} - used to check if a word is already tokenized
-	idToToken map[int]string // {0: hello, 1: world, ...} - used to decode tokens
+	vocab     *vocabulary // id -> token bytes - built on Train/Load
 	vocabSize int
+	Chars     []Char // character alphabet, empty in byte mode
 	Merges    []Merge
+
+	// SentencePiece replaces GPT4_SPLIT_PATTERN pre-tokenization when set
+	SentencePiece *SentencePieceOptions
+
+	// CrossChunks applies merges across pre-tokenizer chunks, like versions
+	// before EncodeWithOffsets did. It is set for text models saved without
+	// the CHUNKED_MARKER line, so their ids do not change.
+	CrossChunks bool
+
+	vocabChars  int // len(Chars) when vocab was built
+	vocabMerges int // len(Merges) when vocab was built
+
+	ranks      map[Pair]int // pair -> rank, built with vocab or on first use by mergeRanks
+	rankMerges int          // len(Merges) when ranks was built
+}
+
+// UnknownTokenError reports a token id that is not in the vocabulary.
+type UnknownTokenError struct {
+	ID       int
+	Position int // index of the id in the decoded slice
+}
+
+func (e *UnknownTokenError) Error() string {
+	return fmt.Sprintf("unknown token id %d at position %d", e.ID, e.Position)
 }
 
 func NewBPETokenizer() *BPETokenizer {
 	tokenizer := &BPETokenizer{
 		Merges:    []Merge{},
-		vocab:     make(map[string]int),
-		idToToken: make(map[int]string),
 		vocabSize: 256,
 	}
-
-	for i := 0; i < 256; i++ {
-		byteStr := string([]byte{byte(i)})
-		tokenizer.vocab[byteStr] = i
-		tokenizer.idToToken[i] = byteStr
-	}
+	tokenizer.buildVocab()
 
 	return tokenizer
 }
 
-func (bpe *BPETokenizer) merge(list []int, pair Pair, index int) []int {
-	newList := []int{}
+// merge replaces every occurrence of pair in tokens with index, in place, and
+// returns the shortened slice
+func (bpe *BPETokenizer) merge(tokens []TokenID, pair Pair, index int) []TokenID {
+	first, second := TokenID(pair.First), TokenID(pair.Second)
 
-	for i := 0; i < len(list); i++ {
-		if i < len(list)-1 && list[i] == pair.First && list[i+1] == pair.Second {
-			newList = append(newList, index)
+	n := 0
+	for i := 0; i < len(tokens); i++ {
+		if i < len(tokens)-1 && tokens[i] == first && tokens[i+1] == second {
+			tokens[n] = TokenID(index)
 			i++ // skip the next element as we merged
 		} else {
-			newList = append(newList, list[i])
+			tokens[n] = tokens[i]
 		}
+		n++
 	}
 
-	return newList
+	return tokens[:n]
 }
 
-func (bpe *BPETokenizer) stats(tokens []int) map[Pair]int {
+func (bpe *BPETokenizer) stats(tokens []TokenID) map[Pair]int {
 	m := make(map[Pair]int)
+	bpe.countPairs(tokens, m)
+	return m
+}
 
+func (bpe *BPETokenizer) countPairs(tokens []TokenID, m map[Pair]int) {
 	for i := 0; i < len(tokens)-1; i++ {
-		curr := tokens[i]
-		next := tokens[i+1]
+		curr := int(tokens[i])
+		next := int(tokens[i+1])
 		pair := Pair{curr, next}
 		m[pair]++
 	}
-
-	return m
 }
 
 func (bpe *BPETokenizer) mostFrequentPair(m map[Pair]int) Pair {
@@ -93,203 +127,486 @@ func (bpe *BPETokenizer) mostFrequentPair(m map[Pair]int) Pair {
 	return maxPair
 }
 
+// chunk is a pre-tokenizer chunk given as byte offsets into the input text
+type chunk struct {
+	start int
+	end   int
+}
+
 /**
- * Tokenize text into bytes
+ * Split text into pre-tokenizer chunks
  * 1. Split text into lines
  * 2. For each line, split into chunks using GPT4_SPLIT_PATTERN
- * 3. For each chunk, convert bytes to int
- * 4. Add newline token if not the last line
+ * 3. Add newline chunk if not the last line
+ * 4. Concatenate the chunks of all lines in order
 **/
-func (bpe *BPETokenizer) Tokenize(text string) []int {
-	fmt.Println("Starting Tokenize")
+func splitChunks(text string) []chunk {
 	if text == "" {
-		return []int{}
+		return nil
 	}
 
 	lines := strings.Split(text, "\n")
 
-	resultChan := make(chan []int, len(lines))
+	results := make([][]chunk, len(lines))
 	var wg sync.WaitGroup
 
+	lineStart := 0
 	for i, line := range lines {
 		wg.Add(1)
-		go func(lineNum int, lineText string) {
+		go func(lineNum int, offset int, lineText string) {
 			defer wg.Done()
 
-			if lineText == "" {
-				resultChan <- []int{}
-				return
-			}
-
-			var lineTokens []int
-			re := regexp2.MustCompile(GPT4_SPLIT_PATTERN, regexp2.None)
-			start := 0
+			var lineChunks []chunk
 
-			for start < len(lineText) {
-				match, err := re.FindStringMatch(lineText[start:])
-				if err != nil || match == nil {
-					break
-				}
+			// regexp2 reports rune positions, so walk the line to turn them into byte offsets
+			start, runePos := 0, 0
+			match, err := splitRegex.FindStringMatch(lineText)
+			for err == nil && match != nil {
+				start = advanceRunes(lineText, start, match.Index-runePos)
+				end := advanceRunes(lineText, start, match.Length)
+				lineChunks = append(lineChunks, chunk{offset + start, offset + end})
 
-				matched := match.String()
-				chunkBytes := []byte(matched)
-				for _, b := range chunkBytes {
-					lineTokens = append(lineTokens, int(b))
-				}
-
-				start += match.Index + len(matched)
+				start, runePos = end, match.Index+match.Length
+				match, err = splitRegex.FindNextMatch(match)
 			}
 
-			// Add newline token if not the last line
+			// Add newline chunk if not the last line
 			if lineNum < len(lines)-1 {
-				lineTokens = append(lineTokens, int('\n'))
+				newline := offset + len(lineText)
+				lineChunks = append(lineChunks, chunk{newline, newline + 1})
 			}
 
-			resultChan <- lineTokens
-		}(i, line)
+			results[lineNum] = lineChunks
+		}(i, lineStart, line)
+		lineStart += len(line) + 1
 	}
 
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+	wg.Wait()
+
+	var chunks []chunk
+	for _, lineChunks := range results {
+		chunks = append(chunks, lineChunks...)
+	}
+
+	return chunks
+}
+
+func advanceRunes(s string, pos int, n int) int {
+	for ; n > 0 && pos < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		pos += size
+	}
+	return pos
+}
 
+/**
+ * Tokenize text into bytes
+ * 1. Split text into pre-tokenizer chunks
+ * 2. For each chunk, convert bytes to int
+**/
+func (bpe *BPETokenizer) Tokenize(text string) []int {
+	fmt.Println("Starting Tokenize")
+	if text == "" {
+		return []int{}
+	}
+
+	text = bpe.normalize(text)
 	var allTokens []int
-	for tokens := range resultChan {
-		allTokens = append(allTokens, tokens...)
+	for _, c := range bpe.chunks(text) {
+		for i := c.start; i < c.end; i++ {
+			allTokens = append(allTokens, int(text[i]))
+		}
 	}
 
 	fmt.Println("Finished Tokenize")
 	return allTokens
 }
 
+// buildVocab lays out the bytes of the base tokens and merges in the arena
+// and builds the merge ranks
+func (bpe *BPETokenizer) buildVocab() {
+	bpe.vocab = newVocabulary(bpe.Chars, bpe.Merges)
+	bpe.vocabChars = len(bpe.Chars)
+	bpe.vocabMerges = len(bpe.Merges)
+	bpe.ranks = nil
+	bpe.mergeRanks()
+}
+
+// charIDs returns the ids of the character alphabet, nil in byte mode where
+// encoding needs no vocabulary
+func (bpe *BPETokenizer) charIDs() map[rune]TokenID {
+	if len(bpe.Chars) == 0 {
+		return nil
+	}
+	return bpe.table().chars
+}
+
+// table returns the vocabulary, rebuilding it if Chars or Merges was changed directly
+func (bpe *BPETokenizer) table() *vocabulary {
+	if bpe.vocab == nil || bpe.vocabChars != len(bpe.Chars) || bpe.vocabMerges != len(bpe.Merges) {
+		bpe.buildVocab()
+	}
+	return bpe.vocab
+}
+
 /**
  * Decode tokens into text
- * 1. Create a local copy of idToToken
- * 2. For each merge, update local copy with merged tokens
- * 3. For each token, convert to byte and append to result
+ * Unknown ids are skipped, use DecodeBytes to have them reported. In
+ * SentencePiece dummy-prefix mode a leading space is taken for the prefix and
+ * dropped, so tokens must start at the beginning of the text; decode a slice
+ * from further in with DecodeOptions.KeepDummyPrefix.
 **/
 func (bpe *BPETokenizer) Decode(tokens []int) string {
 	if len(tokens) == 0 {
 		return ""
 	}
 
-	localVocab := make(map[int]string)
-	for id, tok := range bpe.idToToken {
-		localVocab[id] = tok
-	}
+	table := bpe.table()
 
-	for _, merge := range bpe.Merges {
-		first := localVocab[merge.Pair.First]
-		second := localVocab[merge.Pair.Second]
-		localVocab[merge.Index] = first + second
+	var result []byte
+	for _, token := range tokens {
+		result = append(result, table.token(token)...)
 	}
 
+	return string(trimDummyPrefix(result, bpe.SentencePiece))
+}
+
+// DecodeIDs decodes TokenIDs into text, skipping unknown ids like Decode
+func (bpe *BPETokenizer) DecodeIDs(tokens []TokenID) string {
+	table := bpe.table()
+
 	var result []byte
 	for _, token := range tokens {
-		if tokenStr, exists := localVocab[token]; exists {
-			result = append(result, []byte(tokenStr)...)
+		result = append(result, table.token(int(token))...)
+	}
+
+	return string(trimDummyPrefix(result, bpe.SentencePiece))
+}
+
+// DecodeBytes decodes tokens into raw bytes, failing with an
+// *UnknownTokenError on the first id missing from the vocabulary. It drops
+// the dummy prefix like Decode, AppendDecode keeps it.
+func (bpe *BPETokenizer) DecodeBytes(tokens []int) ([]byte, error) {
+	decoded, err := appendDecode(nil, tokens, bpe.table())
+	return trimDummyPrefix(decoded, bpe.SentencePiece), err
+}
+
+// AppendDecode appends the bytes of tokens to dst and returns the extended
+// slice. It does not allocate when dst has enough capacity. It is meant for
+// streaming, so it keeps the space of a SentencePiece dummy prefix.
+func (bpe *BPETokenizer) AppendDecode(dst []byte, tokens []int) ([]byte, error) {
+	return appendDecode(dst, tokens, bpe.table())
+}
+
+func appendDecode(dst []byte, tokens []int, table *vocabulary) ([]byte, error) {
+	for i, token := range tokens {
+		bytes := table.token(token)
+		if bytes == nil {
+			return dst, &UnknownTokenError{ID: token, Position: i}
 		}
+		dst = append(dst, bytes...)
+	}
+	return dst, nil
+}
+
+/**
+ * Return the pair -> rank lookup, where rank is the position in Merges
+ * 1. Reuse the lookup unless Merges changed since it was built, like table
+ * 2. If a pair appears more than once only the first merge is kept
+ * The lookup is shared and must not be modified.
+**/
+func (bpe *BPETokenizer) mergeRanks() map[Pair]int {
+	if bpe.ranks != nil && bpe.rankMerges == len(bpe.Merges) {
+		return bpe.ranks
 	}
 
-	return string(result)
+	ranks := make(map[Pair]int, len(bpe.Merges))
+	for i, m := range bpe.Merges {
+		if _, exists := ranks[m.Pair]; !exists {
+			ranks[m.Pair] = i
+		}
+	}
+	bpe.ranks, bpe.rankMerges = ranks, len(bpe.Merges)
+	return ranks
+}
+
+/**
+ * Encode a single chunk and append its tokens to dst
+ * 1. Append the base tokens of the chunk, its bytes or characters
+ * 2. Find the adjacent pair with the lowest merge rank
+ * 3. Merge it in place and repeat until no mergeable pair is left
+**/
+func (bpe *BPETokenizer) encodeChunk(dst []TokenID, text string, ranks map[Pair]int) []TokenID {
+	start := len(dst)
+	dst = appendBaseTokens(dst, text, bpe.charIDs())
+
+	tokens := dst[start:]
+	for len(tokens) >= 2 {
+		best := -1
+		for i := 0; i < len(tokens)-1; i++ {
+			rank, exists := ranks[Pair{int(tokens[i]), int(tokens[i+1])}]
+			if exists && (best == -1 || rank < best) {
+				best = rank
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		m := bpe.Merges[best]
+		tokens = bpe.merge(tokens, m.Pair, m.Index)
+	}
+
+	return dst[:start+len(tokens)]
 }
 
 /**
  * Encode text into tokens
- * 1. Tokenize text into bytes
- * 2. For each merge, apply merge to tokens
+ * 1. Split text into pre-tokenizer chunks, or SentencePiece chunks
+ * 2. Encode each chunk independently, merges never cross chunk boundaries
 **/
 func (bpe *BPETokenizer) Encode(text string) []int {
-	tokens := bpe.Tokenize(text)
+	return toInts(bpe.appendEncode(nil, text, bpe.mergeRanks()))
+}
 
-	for _, m := range bpe.Merges {
-		tokens = bpe.merge(tokens, m.Pair, m.Index)
+// EncodeIDs encodes text into TokenIDs, see Encode
+func (bpe *BPETokenizer) EncodeIDs(text string) []TokenID {
+	return bpe.appendEncode(nil, text, bpe.mergeRanks())
+}
+
+// AppendEncode appends the tokens of text to dst and returns the extended
+// slice, so a buffer can be reused across calls.
+func (bpe *BPETokenizer) AppendEncode(dst []TokenID, text string) []TokenID {
+	return bpe.appendEncode(dst, text, bpe.mergeRanks())
+}
+
+// appendEncode is AppendEncode with precomputed merge ranks
+func (bpe *BPETokenizer) appendEncode(dst []TokenID, text string, ranks map[Pair]int) []TokenID {
+	text = bpe.normalize(text)
+	for _, c := range bpe.chunks(text) {
+		dst = bpe.encodeChunk(dst, text[c.start:c.end], ranks)
+	}
+	return dst
+}
+
+// toInts converts TokenIDs to ints, never returning nil
+func toInts(ids []TokenID) []int {
+	ints := make([]int, len(ids))
+	for i, id := range ids {
+		ints[i] = int(id)
 	}
+	return ints
+}
+
+// TrainOptions configures TrainWithOptions
+type TrainOptions struct {
+	VocabSize int // total vocabulary size including the base alphabet, VOCAB_SIZE when 0
+
+	// CharacterCoverage switches to a character base alphabet when > 0: the most
+	// frequent multi-byte characters covering this fraction of the training
+	// text get their own ids, like SentencePiece's character_coverage, and the
+	// remaining characters fall back to bytes. 1 covers every character seen.
+	CharacterCoverage float64
 
-	return tokens
+	// SentencePiece trains and encodes without GPT4_SPLIT_PATTERN, see SentencePieceOptions
+	SentencePiece *SentencePieceOptions
 }
 
+// Train with VOCAB_SIZE tokens and a byte base alphabet
 func (bpe *BPETokenizer) Train(text string) {
+	bpe.TrainWithOptions(text, TrainOptions{})
+}
+
+/**
+ * Train the tokenizer
+ * 1. Select the character alphabet when CharacterCoverage is set
+ * 2. Split text into chunks of base tokens, in SentencePiece mode when set
+ * 3. Repeatedly merge the most frequent pair until the vocabulary is full or
+ *    every chunk is a single token
+**/
+func (bpe *BPETokenizer) TrainWithOptions(text string, opts TrainOptions) {
 	fmt.Println("Starting Training")
-	tokens := bpe.Tokenize(text)
-	numOfMerges := VOCAB_SIZE - 256
+	bpe.CrossChunks = false
+	vocabSize := opts.VocabSize
+	if vocabSize == 0 {
+		vocabSize = VOCAB_SIZE
+	}
+
+	if opts.CharacterCoverage > 0 {
+		bpe.Chars = selectCharacters(text, opts.CharacterCoverage, max(vocabSize-256, 0))
+		bpe.vocabSize += len(bpe.Chars)
+		fmt.Println("Selected", len(bpe.Chars), "characters")
+	}
+	if opts.SentencePiece != nil {
+		spm := *opts.SentencePiece
+		bpe.SentencePiece = &spm
+	}
+	base := 256 + len(bpe.Chars)
+	chars := bpe.charIDs()
+
+	text = bpe.normalize(text)
+	var chunks [][]TokenID
+	for _, c := range bpe.chunks(text) {
+		chunks = append(chunks, appendBaseTokens(make([]TokenID, 0, c.end-c.start), text[c.start:c.end], chars))
+	}
+
+	numOfMerges := vocabSize - base
 	for i := 0; i < numOfMerges; i++ {
 		fmt.Println("Merging number: ", i)
-		statsMap := bpe.stats(tokens)
+		statsMap := make(map[Pair]int)
+		for _, tokens := range chunks {
+			bpe.countPairs(tokens, statsMap)
+		}
 		if len(statsMap) == 0 {
-			for j := i; j < numOfMerges; j++ {
-				dummyPair := Pair{First: 0, Second: 0}
-				idx := 256 + j
-				bpe.Merges = append(bpe.Merges, Merge{dummyPair, idx})
-			}
+			// every chunk is a single token, the vocabulary stays smaller than asked for
+			fmt.Println("No pairs left to merge after", i, "merges")
 			break
 		}
 
-		idx := 256 + i
+		idx := base + i
 		maxUsedPair := bpe.mostFrequentPair(statsMap)
 
-		firstToken := bpe.idToToken[maxUsedPair.First]
-		secondToken := bpe.idToToken[maxUsedPair.Second]
-		mergedToken := firstToken + secondToken
-
-		bpe.vocab[mergedToken] = idx
-		bpe.idToToken[idx] = mergedToken
-
-		tokens = bpe.merge(tokens, maxUsedPair, idx)
+		for j, tokens := range chunks {
+			chunks[j] = bpe.merge(tokens, maxUsedPair, idx)
+		}
 		bpe.Merges = append(bpe.Merges, Merge{maxUsedPair, idx})
+		bpe.vocabSize++
 	}
-	fmt.Println("Finished Training")
+	bpe.buildVocab()
+	fmt.Println("Finished Training with vocab size", bpe.vocabSize)
+}
+
+// VocabSize returns the number of tokens, 256 bytes plus one per character
+// and merge. It is below the requested size when training ran out of pairs.
+func (bpe *BPETokenizer) VocabSize() int {
+	return bpe.vocabSize
+}
+
+// TokenBytes returns a copy of the raw bytes of id, nil if id is not in the
+// vocabulary. Unlike DecodeBytes it never drops a SentencePiece dummy prefix,
+// so the space token is " " and not empty.
+func (bpe *BPETokenizer) TokenBytes(id int) []byte {
+	return slices.Clone(bpe.table().token(id))
 }
 
 func (bpe *BPETokenizer) Save() {
-	fileName := "vocab.model"
+	if err := bpe.SaveFile(MODEL_FILE); err != nil {
+		fmt.Println("Error saving model:", err)
+		return
+	}
+
+	fmt.Println("Vocab saved to", MODEL_FILE)
+}
 
-	file, err := os.Create(fmt.Sprintf("./%s", fileName)) // creates or truncates
+// SaveFile writes the model to path, the CHUNKED_MARKER line unless
+// CrossChunks is set, a "sentencepiece ..." options line in SentencePiece
+// mode, one "U+XXXX index" line per character and one "first-second index"
+// line per merge
+func (bpe *BPETokenizer) SaveFile(path string) error {
+	file, err := os.Create(path) // creates or truncates
 	if err != nil {
-		fmt.Println("Error creating file:", err)
-		return
+		return err
 	}
-	defer file.Close()
 
+	w := bufio.NewWriter(file)
+	if !bpe.CrossChunks {
+		fmt.Fprintln(w, CHUNKED_MARKER)
+	}
+	if bpe.SentencePiece != nil {
+		fmt.Fprintln(w, bpe.SentencePiece.String())
+	}
+	for _, c := range bpe.Chars {
+		fmt.Fprintln(w, c.String(), c.Index)
+	}
 	for _, m := range bpe.Merges {
-		fmt.Fprintln(file, m.Pair.String(), m.Index)
+		fmt.Fprintln(w, m.Pair.String(), m.Index)
 	}
 
-	fmt.Println("Vocab saved to", fileName)
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
 
 func (bpe *BPETokenizer) Load() {
-	fileName := "vocab.model"
+	if err := bpe.LoadFile(MODEL_FILE); err != nil {
+		fmt.Println("Error loading model:", err)
+	}
+}
 
-	file, err := os.Open(fmt.Sprintf("./%s", fileName))
+// LoadFile reads a model written by SaveFile or SaveBinaryFile
+func (bpe *BPETokenizer) LoadFile(path string) error {
+	file, err := os.Open(path)
 	if err != nil {
-		fmt.Println("Error opening file:", err)
-		return
+		return err
 	}
 	defer file.Close()
 
-	// Reset and reinitialize base vocabulary
-	bpe.vocab = make(map[string]int)
-	bpe.idToToken = make(map[int]string)
-	bpe.vocabSize = 256
-	bpe.Merges = []Merge{}
+	if err := bpe.LoadReader(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
 
-	// Initialize base vocabulary with all 256 possible bytes
-	for i := 0; i < 256; i++ {
-		byteStr := string([]byte{byte(i)})
-		bpe.vocab[byteStr] = i
-		bpe.idToToken[i] = byteStr
+/**
+ * Load a model from r
+ * 1. Hand models in the binary format to LoadBinary
+ * 2. Reset to the base vocabulary
+ * 3. Parse the CHUNKED_MARKER and SentencePiece options, then one
+ *    "U+XXXX index" character or "first-second index" merge per line
+ * 4. Drop legacy padding merges
+ * 5. Merge across chunks if the model has merges but no marker. Characters
+ *    and SentencePiece options came later than the marker, so they imply it
+ * 6. Build the vocabulary
+**/
+func (bpe *BPETokenizer) LoadReader(r io.Reader) error {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(len(BINARY_MAGIC)); IsBinaryModel(magic) {
+		data, err := io.ReadAll(buffered)
+		if err != nil {
+			return err
+		}
+		return bpe.LoadBinary(data)
 	}
 
-	scanner := bufio.NewScanner(file)
+	bpe.vocabSize = 256
+	bpe.Chars = nil
+	bpe.Merges = []Merge{}
+	bpe.SentencePiece = nil
+
+	scanner := bufio.NewScanner(buffered)
 
+	chunked := false
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := scanner.Text()
+		if line == CHUNKED_MARKER {
+			chunked = true
+			continue
+		}
+		if strings.HasPrefix(line, "sentencepiece") {
+			opts, err := parseSentencePieceLine(line)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			bpe.SentencePiece = opts
+			continue
+		}
+		if strings.HasPrefix(line, "U+") {
+			var c Char
+			if _, err := fmt.Sscanf(line, "U+%X %d", &c.Rune, &c.Index); err != nil {
+				return fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			bpe.Chars = append(bpe.Chars, c)
+			continue
+		}
+
 		var first, second, index int
 		_, err := fmt.Sscanf(line, "%d-%d %d", &first, &second, &index)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("line %d: %w", lineNum, err)
 		}
 
 		merge := Merge{
@@ -297,13 +614,37 @@ func (bpe *BPETokenizer) Load() {
 			Index: index,
 		}
 		bpe.Merges = append(bpe.Merges, merge)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
 
-		if first < len(bpe.idToToken) && second < len(bpe.idToToken) {
-			firstToken := bpe.idToToken[first]
-			secondToken := bpe.idToToken[second]
-			mergedToken := firstToken + secondToken
-			bpe.vocab[mergedToken] = index
-			bpe.idToToken[index] = mergedToken
-		}
+	bpe.dropPadding()
+	chunked = chunked || bpe.SentencePiece != nil || len(bpe.Chars) > 0
+	bpe.CrossChunks = !chunked && len(bpe.Merges) > 0
+	bpe.vocabSize += len(bpe.Chars) + len(bpe.Merges)
+	bpe.buildVocab()
+	return nil
+}
+
+/**
+ * Find the dummy Pair{0,0} merges older versions of Train appended when they
+ * ran out of pairs, returning the index of the first one in Merges
+ * 1. Find the trailing run of Pair{0,0} merges
+ * 2. A lone one is not padding, it is indistinguishable from a real merge of two NUL bytes
+**/
+func (bpe *BPETokenizer) paddingStart() int {
+	end := len(bpe.Merges)
+	for end > 0 && bpe.Merges[end-1].Pair == (Pair{0, 0}) {
+		end--
+	}
+	if len(bpe.Merges)-end < 2 {
+		return len(bpe.Merges)
 	}
+	return end
+}
+
+// dropPadding removes legacy padding merges
+func (bpe *BPETokenizer) dropPadding() {
+	bpe.Merges = bpe.Merges[:bpe.paddingStart()]
 }
@@ -1,7 +1,14 @@
 package bpe
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -13,11 +20,7 @@ func TestNewBPETokenizer(t *testing.T) {
 	}
 
 	if tokenizer.vocab == nil {
-		t.Error("vocab map should be initialized")
-	}
-
-	if tokenizer.idToToken == nil {
-		t.Error("idToToken map should be initialized")
+		t.Error("vocab should be initialized")
 	}
 
 	if tokenizer.vocabSize != 256 {
@@ -25,20 +28,16 @@ func TestNewBPETokenizer(t *testing.T) {
 	}
 
 	// Check that base vocabulary is properly initialized with all 256 bytes
-	if len(tokenizer.vocab) != 256 {
-		t.Errorf("vocab should contain 256 base tokens, got %d", len(tokenizer.vocab))
-	}
-
-	if len(tokenizer.idToToken) != 256 {
-		t.Errorf("idToToken should contain 256 base tokens, got %d", len(tokenizer.idToToken))
+	if tokenizer.vocab.size() != 256 {
+		t.Errorf("vocab should contain 256 base tokens, got %d", tokenizer.vocab.size())
 	}
 
 	// Check a few specific byte mappings
-	if tokenizer.vocab[string([]byte{0})] != 0 {
-		t.Error("byte 0 should map to token 0")
+	if !reflect.DeepEqual(tokenizer.vocab.token(0), []byte{0}) {
+		t.Error("token 0 should be byte 0")
 	}
-	if tokenizer.vocab[string([]byte{255})] != 255 {
-		t.Error("byte 255 should map to token 255")
+	if !reflect.DeepEqual(tokenizer.vocab.token(255), []byte{255}) {
+		t.Error("token 255 should be byte 255")
 	}
 
 	if tokenizer.Merges == nil {
@@ -88,52 +87,52 @@ func TestMerge(t *testing.T) {
 
 	tests := []struct {
 		name     string
-		list     []int
+		list     []TokenID
 		pair     Pair
 		index    int
-		expected []int
+		expected []TokenID
 	}{
 		{
 			name:     "simple merge",
-			list:     []int{1, 2, 3, 4},
+			list:     []TokenID{1, 2, 3, 4},
 			pair:     Pair{First: 1, Second: 2},
 			index:    10,
-			expected: []int{10, 3, 4},
+			expected: []TokenID{10, 3, 4},
 		},
 		{
 			name:     "multiple merges",
-			list:     []int{1, 2, 3, 1, 2, 5},
+			list:     []TokenID{1, 2, 3, 1, 2, 5},
 			pair:     Pair{First: 1, Second: 2},
 			index:    10,
-			expected: []int{10, 3, 10, 5},
+			expected: []TokenID{10, 3, 10, 5},
 		},
 		{
 			name:     "no merge found",
-			list:     []int{1, 3, 4, 5},
+			list:     []TokenID{1, 3, 4, 5},
 			pair:     Pair{First: 1, Second: 2},
 			index:    10,
-			expected: []int{1, 3, 4, 5},
+			expected: []TokenID{1, 3, 4, 5},
 		},
 		{
 			name:     "merge at end",
-			list:     []int{3, 4, 1, 2},
+			list:     []TokenID{3, 4, 1, 2},
 			pair:     Pair{First: 1, Second: 2},
 			index:    10,
-			expected: []int{3, 4, 10},
+			expected: []TokenID{3, 4, 10},
 		},
 		{
 			name:     "empty list",
-			list:     []int{},
+			list:     []TokenID{},
 			pair:     Pair{First: 1, Second: 2},
 			index:    10,
-			expected: []int{},
+			expected: []TokenID{},
 		},
 		{
 			name:     "single element",
-			list:     []int{1},
+			list:     []TokenID{1},
 			pair:     Pair{First: 1, Second: 2},
 			index:    10,
-			expected: []int{1},
+			expected: []TokenID{1},
 		},
 	}
 
@@ -152,12 +151,12 @@ func TestStats(t *testing.T) {
 
 	tests := []struct {
 		name     string
-		tokens   []int
+		tokens   []TokenID
 		expected map[Pair]int
 	}{
 		{
 			name:   "simple sequence",
-			tokens: []int{1, 2, 3, 4},
+			tokens: []TokenID{1, 2, 3, 4},
 			expected: map[Pair]int{
 				{1, 2}: 1,
 				{2, 3}: 1,
@@ -166,7 +165,7 @@ func TestStats(t *testing.T) {
 		},
 		{
 			name:   "repeated pairs",
-			tokens: []int{1, 2, 1, 2, 3},
+			tokens: []TokenID{1, 2, 1, 2, 3},
 			expected: map[Pair]int{
 				{1, 2}: 2,
 				{2, 1}: 1,
@@ -175,17 +174,17 @@ func TestStats(t *testing.T) {
 		},
 		{
 			name:     "single token",
-			tokens:   []int{1},
+			tokens:   []TokenID{1},
 			expected: map[Pair]int{},
 		},
 		{
 			name:     "empty tokens",
-			tokens:   []int{},
+			tokens:   []TokenID{},
 			expected: map[Pair]int{},
 		},
 		{
 			name:   "two tokens",
-			tokens: []int{1, 2},
+			tokens: []TokenID{1, 2},
 			expected: map[Pair]int{
 				{1, 2}: 1,
 			},
@@ -272,16 +271,12 @@ func TestTokenize(t *testing.T) {
 					t.Error("Expected non-empty tokens")
 				}
 				// Check that vocab was populated
-				if len(tokenizer.vocab) == 0 {
+				if tokenizer.vocab.size() == 0 {
 					t.Error("Expected vocab to be populated")
 				}
-				// Check that idToToken was populated
-				if len(tokenizer.idToToken) == 0 {
-					t.Error("Expected idToToken to be populated")
-				}
 				// Check that vocabSize matches
-				if tokenizer.vocabSize != len(tokenizer.vocab) {
-					t.Errorf("vocabSize (%d) should match vocab length (%d)", tokenizer.vocabSize, len(tokenizer.vocab))
+				if tokenizer.vocabSize != tokenizer.vocab.size() {
+					t.Errorf("vocabSize (%d) should match vocab size (%d)", tokenizer.vocabSize, tokenizer.vocab.size())
 				}
 			},
 		},
@@ -316,7 +311,7 @@ func TestTokenize(t *testing.T) {
 				}
 				// The same word should get the same token ID
 				// We can't predict exact structure due to regex complexity, but vocab should be consistent
-				if len(tokenizer.vocab) == 0 {
+				if tokenizer.vocab.size() == 0 {
 					t.Error("Expected vocab to be populated")
 				}
 			},
@@ -341,15 +336,19 @@ func TestDecode(t *testing.T) {
 		{
 			name: "simple decode",
 			setup: func(tokenizer *BPETokenizer) []int {
-				// Manually set up vocab
-				tokenizer.vocab["hello"] = 0
-				tokenizer.vocab[" "] = 1
-				tokenizer.vocab["world"] = 2
-				tokenizer.idToToken[0] = "hello"
-				tokenizer.idToToken[1] = " "
-				tokenizer.idToToken[2] = "world"
-				tokenizer.vocabSize = 3
-				return []int{0, 1, 2}
+				// Manually set up merges for "hello" (259) and "world" (262)
+				tokenizer.Merges = []Merge{
+					{Pair{'h', 'e'}, 256},
+					{Pair{256, 'l'}, 257},
+					{Pair{257, 'l'}, 258},
+					{Pair{258, 'o'}, 259},
+					{Pair{'w', 'o'}, 260},
+					{Pair{260, 'r'}, 261},
+					{Pair{261, 'l'}, 262},
+					{Pair{262, 'd'}, 263},
+				}
+				tokenizer.vocabSize = 256 + len(tokenizer.Merges)
+				return []int{259, ' ', 263}
 			},
 			validate: func(t *testing.T, result string) {
 				expected := "hello world"
@@ -372,16 +371,9 @@ func TestDecode(t *testing.T) {
 		{
 			name: "with merges",
 			setup: func(tokenizer *BPETokenizer) []int {
-				// Set up base vocab
-				tokenizer.vocab["h"] = 0
-				tokenizer.vocab["e"] = 1
-				tokenizer.idToToken[0] = "h"
-				tokenizer.idToToken[1] = "e"
-				tokenizer.vocabSize = 2
-
 				// Add a merge
 				merge := Merge{
-					Pair:  Pair{First: 0, Second: 1},
+					Pair:  Pair{First: 'h', Second: 'e'},
 					Index: 256,
 				}
 				tokenizer.Merges = append(tokenizer.Merges, merge)
@@ -443,13 +435,8 @@ func TestEncode(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tokenizer := NewBPETokenizer()
 			// Add some dummy merges to test the encoding process
-			tokenizer.vocab["h"] = 0
-			tokenizer.vocab["e"] = 1
-			tokenizer.idToToken[0] = "h"
-			tokenizer.idToToken[1] = "e"
-			tokenizer.vocabSize = 2
 			tokenizer.Merges = append(tokenizer.Merges, Merge{
-				Pair:  Pair{First: 0, Second: 1},
+				Pair:  Pair{First: 'h', Second: 'e'},
 				Index: 256,
 			})
 
@@ -469,10 +456,20 @@ func TestTrain(t *testing.T) {
 			name: "simple training",
 			text: "hello hello world world",
 			validate: func(t *testing.T, tokenizer *BPETokenizer) {
-				// After training, should have merges
-				expectedMerges := VOCAB_SIZE - 256
-				if len(tokenizer.Merges) != expectedMerges {
-					t.Errorf("Expected %d merges, got %d", expectedMerges, len(tokenizer.Merges))
+				// Training stops once every chunk is a single token
+				if len(tokenizer.Merges) == 0 || len(tokenizer.Merges) >= VOCAB_SIZE-256 {
+					t.Errorf("Expected between 1 and %d merges, got %d", VOCAB_SIZE-257, len(tokenizer.Merges))
+				}
+				if got := tokenizer.Encode("hello hello world world"); len(got) != 4 {
+					t.Errorf("Expected one token per chunk, got %v", got)
+				}
+				if tokenizer.VocabSize() != 256+len(tokenizer.Merges) {
+					t.Errorf("VocabSize() = %d, want %d", tokenizer.VocabSize(), 256+len(tokenizer.Merges))
+				}
+				for _, merge := range tokenizer.Merges {
+					if merge.Pair == (Pair{0, 0}) {
+						t.Errorf("Unexpected padding merge %v", merge)
+					}
 				}
 
 				// Merges should have valid indices starting from 256
@@ -484,7 +481,7 @@ func TestTrain(t *testing.T) {
 				}
 
 				// Vocab should be populated
-				if len(tokenizer.vocab) == 0 {
+				if tokenizer.vocab.size() == 0 {
 					t.Error("Expected vocab to be populated after training")
 				}
 			},
@@ -493,10 +490,8 @@ func TestTrain(t *testing.T) {
 			name: "empty text training",
 			text: "",
 			validate: func(t *testing.T, tokenizer *BPETokenizer) {
-				// Training on empty text should still create merges (though they might be empty)
-				expectedMerges := VOCAB_SIZE - 256
-				if len(tokenizer.Merges) != expectedMerges {
-					t.Errorf("Expected %d merges even for empty text, got %d", expectedMerges, len(tokenizer.Merges))
+				if len(tokenizer.Merges) != 0 || tokenizer.VocabSize() != 256 {
+					t.Errorf("Expected no merges for empty text, got %d (vocab size %d)", len(tokenizer.Merges), tokenizer.VocabSize())
 				}
 			},
 		},
@@ -504,17 +499,40 @@ func TestTrain(t *testing.T) {
 			name: "single character training",
 			text: "a",
 			validate: func(t *testing.T, tokenizer *BPETokenizer) {
-				expectedMerges := VOCAB_SIZE - 256
-				if len(tokenizer.Merges) != expectedMerges {
-					t.Errorf("Expected %d merges, got %d", expectedMerges, len(tokenizer.Merges))
+				if len(tokenizer.Merges) != 0 {
+					t.Errorf("Expected no merges, got %d", len(tokenizer.Merges))
 				}
 
 				// Should have at least one token in vocab
-				if len(tokenizer.vocab) == 0 {
+				if tokenizer.vocab.size() == 0 {
 					t.Error("Expected vocab to be populated")
 				}
 			},
 		},
+		{
+			name: "two character training",
+			text: "ab",
+			validate: func(t *testing.T, tokenizer *BPETokenizer) {
+				if len(tokenizer.Merges) != 1 || tokenizer.Merges[0] != (Merge{Pair{'a', 'b'}, 256}) {
+					t.Errorf("Expected the single merge a-b, got %v", tokenizer.Merges)
+				}
+				if tokenizer.VocabSize() != 257 {
+					t.Errorf("VocabSize() = %d, want 257", tokenizer.VocabSize())
+				}
+			},
+		},
+		{
+			name: "NUL bytes stay separate from padding",
+			text: "a\x00\x00",
+			validate: func(t *testing.T, tokenizer *BPETokenizer) {
+				if got := tokenizer.Decode(tokenizer.Encode("a\x00\x00")); got != "a\x00\x00" {
+					t.Errorf("Round trip gave %q", got)
+				}
+				if got := tokenizer.Encode("\x00"); len(got) != 1 || got[0] != 0 {
+					t.Errorf("Encode(NUL) = %v, want [0]", got)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -554,3 +572,352 @@ func TestIntegration(t *testing.T) {
 		t.Errorf("Integration test with new text failed: original=%q, decoded=%q", newText, decoded2)
 	}
 }
+
+func TestDecodeBytes(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.Merges = append(tokenizer.Merges, Merge{Pair: Pair{'h', 'e'}, Index: 256})
+
+	tests := []struct {
+		name     string
+		tokens   []int
+		expected []byte
+		err      *UnknownTokenError
+	}{
+		{
+			name:     "known ids",
+			tokens:   []int{256, 'l', 'l', 'o'},
+			expected: []byte("hello"),
+		},
+		{
+			name:     "raw bytes",
+			tokens:   []int{0xE6, 0x97},
+			expected: []byte{0xE6, 0x97},
+		},
+		{
+			name:   "unknown id",
+			tokens: []int{'h', 300, 'o'},
+			err:    &UnknownTokenError{ID: 300, Position: 1},
+		},
+		{
+			name:   "negative id",
+			tokens: []int{-1},
+			err:    &UnknownTokenError{ID: -1, Position: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tokenizer.DecodeBytes(tt.tokens)
+			if tt.err != nil {
+				if !reflect.DeepEqual(err, tt.err) {
+					t.Errorf("DecodeBytes() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeBytes() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("DecodeBytes() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAppendDecode(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.Train("hello world hello world")
+
+	tokens := tokenizer.Encode("hello world")
+	dst := make([]byte, 0, 64)
+
+	result, err := tokenizer.AppendDecode(dst, tokens)
+	if err != nil {
+		t.Fatalf("AppendDecode() error = %v", err)
+	}
+	if string(result) != "hello world" {
+		t.Errorf("AppendDecode() = %q, want %q", result, "hello world")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dst, _ = tokenizer.AppendDecode(dst[:0], tokens)
+	})
+	if allocs != 0 {
+		t.Errorf("AppendDecode() allocated %v times, want 0", allocs)
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	text := "hello world hello world"
+	tokenizer.Train(text)
+
+	path := t.TempDir() + "/test.model"
+	if err := tokenizer.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+
+	loaded := NewBPETokenizer()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded.Merges, tokenizer.Merges) {
+		t.Error("loaded merges differ from saved merges")
+	}
+	if !reflect.DeepEqual(loaded.Encode(text), tokenizer.Encode(text)) {
+		t.Error("loaded model encodes differently")
+	}
+	if loaded.Decode(loaded.Encode(text)) != text {
+		t.Error("loaded model does not round trip")
+	}
+}
+
+func TestLoadLegacyPadding(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string
+		expected []Merge
+	}{
+		{
+			name:     "padding dropped",
+			model:    "104-101 256\n256-108 257\n0-0 258\n0-0 259\n0-0 260\n",
+			expected: []Merge{{Pair{104, 101}, 256}, {Pair{256, 108}, 257}},
+		},
+		{
+			name:     "only padding",
+			model:    "0-0 256\n0-0 257\n",
+			expected: []Merge{},
+		},
+		{
+			name:     "real NUL merge kept",
+			model:    "97-98 256\n0-0 257\n",
+			expected: []Merge{{Pair{97, 98}, 256}, {Pair{0, 0}, 257}},
+		},
+		{
+			name:     "NUL merge before other merges kept",
+			model:    "0-0 256\n97-98 257\n",
+			expected: []Merge{{Pair{0, 0}, 256}, {Pair{97, 98}, 257}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := NewBPETokenizer()
+			if err := tokenizer.LoadReader(strings.NewReader(tt.model)); err != nil {
+				t.Fatalf("LoadReader: %v", err)
+			}
+			if !reflect.DeepEqual(tokenizer.Merges, tt.expected) {
+				t.Errorf("Merges = %v, want %v", tokenizer.Merges, tt.expected)
+			}
+			if tokenizer.VocabSize() != 256+len(tt.expected) {
+				t.Errorf("VocabSize() = %d, want %d", tokenizer.VocabSize(), 256+len(tt.expected))
+			}
+			if _, err := tokenizer.DecodeBytes([]int{256 + len(tt.expected)}); err == nil {
+				t.Errorf("id %d should be unknown after loading", 256+len(tt.expected))
+			}
+		})
+	}
+}
+
+func TestLoadCrossChunks(t *testing.T) {
+	// "o " (256) spans the chunks "hello" and " world"
+	model := "111-32 256\n104-101 257\n"
+	text := "hello world"
+
+	legacy := NewBPETokenizer()
+	if err := legacy.LoadReader(strings.NewReader(model)); err != nil {
+		t.Fatalf("LoadReader: %v", err)
+	}
+	chunked := NewBPETokenizer()
+	if err := chunked.LoadReader(strings.NewReader(CHUNKED_MARKER + "\n" + model)); err != nil {
+		t.Fatalf("LoadReader: %v", err)
+	}
+	if !legacy.CrossChunks || chunked.CrossChunks {
+		t.Fatalf("CrossChunks = %v, %v, want true without the marker only", legacy.CrossChunks, chunked.CrossChunks)
+	}
+
+	// without the marker, merges apply in order over the whole text like before
+	var ids []TokenID
+	for _, b := range legacy.Tokenize(text) {
+		ids = append(ids, TokenID(b))
+	}
+	for _, m := range legacy.Merges {
+		ids = legacy.merge(ids, m.Pair, m.Index)
+	}
+	tokens := toInts(ids)
+	for name, got := range map[string][]int{
+		"Encode":          legacy.Encode(text),
+		"Freeze().Encode": legacy.Freeze().Encode(text),
+	} {
+		if !reflect.DeepEqual(got, tokens) {
+			t.Errorf("%s() = %v, want %v", name, got, tokens)
+		}
+	}
+	if got := chunked.Encode(text); slices.Contains(got, 256) {
+		t.Errorf("chunked Encode() = %v, want no \"o \" token", got)
+	}
+	if got := legacy.Decode(legacy.Encode(text)); got != text {
+		t.Errorf("Decode(Encode()) = %q, want %q", got, text)
+	}
+
+	// the mode survives saving, binary models are chunked only
+	for _, tokenizer := range []*BPETokenizer{legacy, chunked} {
+		path := filepath.Join(t.TempDir(), "model")
+		if err := tokenizer.SaveFile(path); err != nil {
+			t.Fatalf("SaveFile: %v", err)
+		}
+		loaded := NewBPETokenizer()
+		if err := loaded.LoadFile(path); err != nil {
+			t.Fatalf("LoadFile: %v", err)
+		}
+		if loaded.CrossChunks != tokenizer.CrossChunks {
+			t.Errorf("LoadFile(SaveFile()).CrossChunks = %v, want %v", loaded.CrossChunks, tokenizer.CrossChunks)
+		}
+	}
+	if err := legacy.WriteBinary(io.Discard); err == nil {
+		t.Error("WriteBinary: expected an error for a CrossChunks model")
+	}
+	if _, err := MergeModels(legacy, chunked, 0); err == nil {
+		t.Error("MergeModels: expected an error for different chunking")
+	}
+
+	legacy.Train("hello world")
+	if legacy.CrossChunks {
+		t.Error("Train() kept CrossChunks, training always stays within chunks")
+	}
+}
+
+func TestLoadReaderInvalid(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	if err := tokenizer.LoadReader(strings.NewReader("104-101 256\nnot a merge\n")); err == nil {
+		t.Error("expected error for malformed model")
+	}
+	if err := tokenizer.LoadFile(t.TempDir() + "/missing.model"); err == nil {
+		t.Error("expected error for missing model file")
+	}
+}
+
+var (
+	benchOnce      sync.Once
+	benchText      string
+	benchTokenizer *BPETokenizer
+)
+
+/**
+ * Load the benchmark corpus and train a tokenizer on it once
+ * 1. Use the first MiB of the Simple Wikipedia training text if it was downloaded
+ * 2. Otherwise fall back to the sample in testdata
+**/
+func benchmarkSetup(b *testing.B) (*BPETokenizer, string) {
+	benchOnce.Do(func() {
+		data, err := readPrefix("../training_text.txt", 1<<20)
+		if err != nil {
+			data, err = os.ReadFile("testdata/simplewiki_sample.txt")
+		}
+		if err != nil {
+			return
+		}
+		benchText = string(data)
+		benchTokenizer = NewBPETokenizer()
+		benchTokenizer.Train(benchText)
+	})
+	if benchTokenizer == nil {
+		b.Fatal("no benchmark corpus")
+	}
+	return benchTokenizer, benchText
+}
+
+func readPrefix(path string, n int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, n))
+}
+
+func BenchmarkEncode(b *testing.B) {
+	tokenizer, text := benchmarkSetup(b)
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tokenizer.Encode(text)
+	}
+}
+
+// AppendEncode into a reused buffer, compare allocations with BenchmarkEncode
+func BenchmarkAppendEncode(b *testing.B) {
+	tokenizer, text := benchmarkSetup(b)
+	var buf []TokenID
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = tokenizer.AppendEncode(buf[:0], text)
+	}
+}
+
+func BenchmarkCount(b *testing.B) {
+	tokenizer, text := benchmarkSetup(b)
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tokenizer.Count(text)
+	}
+}
+
+// TruncateToTokens on ten copies of the corpus, cutting at several budgets
+func BenchmarkTruncateToTokens(b *testing.B) {
+	tokenizer, text := benchmarkSetup(b)
+	text = strings.Repeat(text, 10)
+	for _, n := range []int{100, 500, 2000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tokenizer.TruncateToTokens(text, n, Prefix)
+				tokenizer.TruncateToTokens(text, n, Suffix)
+			}
+		})
+	}
+}
+
+// Allocations of loading a model and building its vocabulary
+func BenchmarkLoadReader(b *testing.B) {
+	tokenizer, _ := benchmarkSetup(b)
+	var model strings.Builder
+	for _, m := range tokenizer.Merges {
+		fmt.Fprintln(&model, m.Pair.String(), m.Index)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := NewBPETokenizer().LoadReader(strings.NewReader(model.String())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Compare the Encoder with and without the chunk cache, every pass encodes
+// with a fresh encoder so hits come only from repetition within the text
+func BenchmarkEncoderEncode(b *testing.B) {
+	tokenizer, text := benchmarkSetup(b)
+
+	for _, size := range []int{0, 1024, DEFAULT_CACHE_SIZE} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			var encoder *Encoder
+			b.SetBytes(int64(len(text)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				encoder = tokenizer.FreezeWithCache(size)
+				b.StartTimer()
+				encoder.Encode(text)
+			}
+			b.ReportMetric(encoder.CacheStats().HitRate(), "hit-rate")
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package bpe
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// Number of chunks an Encoder from Freeze caches
+const DEFAULT_CACHE_SIZE = 4096
+
+// CacheStats reports how well the chunk cache of an Encoder is doing
+type CacheStats struct {
+	Hits     uint64
+	Misses   uint64
+	Entries  int
+	Capacity int
+}
+
+// HitRate returns the fraction of chunk lookups served from the cache
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// chunkCache is a bounded LRU cache from chunk bytes to their token ids, safe
+// for concurrent use. Cached slices are shared and must not be modified.
+type chunkCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // most recently used first
+	hits     uint64
+	misses   uint64
+}
+
+type cacheEntry struct {
+	chunk string
+	ids   []TokenID
+}
+
+func newChunkCache(capacity int) *chunkCache {
+	return &chunkCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+func (c *chunkCache) get(chunk string) ([]TokenID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[chunk]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).ids, true
+}
+
+/**
+ * Add the ids of a chunk to the cache
+ * 1. Copy the chunk so the cache does not keep the whole input text alive
+ * 2. Evict the least recently used entry when full
+**/
+func (c *chunkCache) add(chunk string, ids []TokenID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.items[chunk]; ok {
+		return
+	}
+
+	chunk = strings.Clone(chunk)
+	c.items[chunk] = c.order.PushFront(&cacheEntry{chunk, ids})
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).chunk)
+	}
+}
+
+func (c *chunkCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:     c.hits,
+		Misses:   c.misses,
+		Entries:  c.order.Len(),
+		Capacity: c.capacity,
+	}
+}
@@ -0,0 +1,99 @@
+package bpe
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestChunkCacheEviction(t *testing.T) {
+	cache := newChunkCache(2)
+	cache.add("a", []TokenID{1})
+	cache.add("b", []TokenID{2})
+
+	// touching "a" makes "b" the least recently used
+	if ids, ok := cache.get("a"); !ok || !reflect.DeepEqual(ids, []TokenID{1}) {
+		t.Fatalf("get(a) = %v, %v", ids, ok)
+	}
+	cache.add("c", []TokenID{3})
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, chunk := range []string{"a", "c"} {
+		if _, ok := cache.get(chunk); !ok {
+			t.Errorf("%s should be cached", chunk)
+		}
+	}
+
+	stats := cache.stats()
+	want := CacheStats{Hits: 3, Misses: 1, Entries: 2, Capacity: 2}
+	if stats != want {
+		t.Errorf("stats() = %+v, want %+v", stats, want)
+	}
+	if got := stats.HitRate(); got != 0.75 {
+		t.Errorf("HitRate() = %v, want 0.75", got)
+	}
+}
+
+func TestEncoderCache(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.Train("hello world hello world")
+	text := "hello world hello world hello"
+
+	tests := []struct {
+		name      string
+		cacheSize int
+		expected  CacheStats
+	}{
+		{"disabled", 0, CacheStats{}},
+		// chunks: "hello" " world" " hello" " world" " hello"
+		{"large", 16, CacheStats{Hits: 2, Misses: 3, Entries: 3, Capacity: 16}},
+		{"evicting", 1, CacheStats{Hits: 0, Misses: 5, Entries: 1, Capacity: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := tokenizer.FreezeWithCache(tt.cacheSize)
+			if got, want := encoder.Encode(text), tokenizer.Encode(text); !reflect.DeepEqual(got, want) {
+				t.Errorf("Encode() = %v, want %v", got, want)
+			}
+			if got := encoder.CacheStats(); got != tt.expected {
+				t.Errorf("CacheStats() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// Run with -race, a tiny cache keeps evicting while goroutines share it
+func TestEncoderCacheConcurrentUse(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.Train("the quick brown fox jumps over the lazy dog")
+	encoder := tokenizer.FreezeWithCache(3)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				text := fmt.Sprintf("the quick fox %d jumps over the dog %d", g, i)
+				if got, want := encoder.Encode(text), tokenizer.Encode(text); !reflect.DeepEqual(got, want) {
+					errs <- fmt.Errorf("goroutine %d: Encode(%q) = %v, want %v", g, text, got, want)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if stats := encoder.CacheStats(); stats.Entries != 3 || stats.Hits+stats.Misses == 0 {
+		t.Errorf("CacheStats() = %+v", stats)
+	}
+}
@@ -0,0 +1,90 @@
+package bpe
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Char is a multi-byte character with its own id in the base alphabet, so it
+// is one token before any merge instead of two to four byte tokens.
+type Char struct {
+	Rune  rune
+	Index int
+}
+
+func (c Char) String() string {
+	return fmt.Sprintf("U+%04X", c.Rune)
+}
+
+/**
+ * Select the characters of the base alphabet
+ * 1. Count every character of text, single-byte characters are always
+ *    covered by their byte token
+ * 2. Take multi-byte characters from most to least frequent until they and the
+ *    single-byte ones cover the coverage fraction of all characters
+ * 3. Keep at most limit characters and give them ids from 256
+**/
+func selectCharacters(text string, coverage float64, limit int) []Char {
+	counts := make(map[rune]int)
+	total, covered := 0, 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		total++
+		if size == 1 {
+			covered++ // ASCII and invalid bytes are byte tokens
+		} else {
+			counts[r]++
+		}
+	}
+
+	runes := make([]rune, 0, len(counts))
+	for r := range counts {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool {
+		if counts[runes[i]] != counts[runes[j]] {
+			return counts[runes[i]] > counts[runes[j]]
+		}
+		return runes[i] < runes[j]
+	})
+
+	chars := []Char{}
+	for _, r := range runes {
+		if float64(covered) >= coverage*float64(total) || len(chars) >= limit {
+			break
+		}
+		chars = append(chars, Char{Rune: r, Index: 256 + len(chars)})
+		covered += counts[r]
+	}
+	return chars
+}
+
+/**
+ * Append the base tokens of text to dst
+ * 1. Without a character alphabet every byte is a token
+ * 2. Otherwise characters of the alphabet are one token each, and every other
+ *    character falls back to its bytes
+**/
+func appendBaseTokens(dst []TokenID, text string, chars map[rune]TokenID) []TokenID {
+	if len(chars) == 0 {
+		for i := 0; i < len(text); i++ {
+			dst = append(dst, TokenID(text[i]))
+		}
+		return dst
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if id, ok := chars[r]; ok && size > 1 {
+			dst = append(dst, id)
+		} else {
+			for j := i; j < i+size; j++ {
+				dst = append(dst, TokenID(text[j]))
+			}
+		}
+		i += size
+	}
+	return dst
+}
@@ -0,0 +1,130 @@
+package bpe
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectCharacters(t *testing.T) {
+	// 4 ascii, 3 日, 2 本, 1 語
+	text := "ab日日日本本語cd"
+
+	tests := []struct {
+		name     string
+		coverage float64
+		limit    int
+		expected []Char
+	}{
+		{"ascii covers enough", 0.4, 10, []Char{}},
+		{"most frequent first", 0.7, 10, []Char{{'日', 256}}},
+		{"ties broken by rune", 0.8, 10, []Char{{'日', 256}, {'本', 257}}},
+		{"full coverage", 1, 10, []Char{{'日', 256}, {'本', 257}, {'語', 258}}},
+		{"limit", 1, 2, []Char{{'日', 256}, {'本', 257}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectCharacters(text, tt.coverage, tt.limit)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("selectCharacters() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAppendBaseTokens(t *testing.T) {
+	chars := map[rune]TokenID{'日': 256, 'é': 257}
+
+	tests := []struct {
+		name     string
+		text     string
+		chars    map[rune]TokenID
+		expected []TokenID
+	}{
+		{"byte mode", "a日", nil, []TokenID{'a', 0xe6, 0x97, 0xa5}},
+		{"known characters", "a日é", chars, []TokenID{'a', 256, 257}},
+		{"byte fallback", "本é", chars, []TokenID{0xe6, 0x9c, 0xac, 257}},
+		{"invalid utf-8", "\xff日", chars, []TokenID{0xff, 256}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appendBaseTokens(nil, tt.text, tt.chars)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("appendBaseTokens() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrainWithCharacterCoverage(t *testing.T) {
+	corpus := strings.Repeat("日本語の文章です。東京は日本の首都です。\n", 20) + "hello world\n"
+	opts := TrainOptions{VocabSize: 300, CharacterCoverage: 1}
+
+	bytesOnly := NewBPETokenizer()
+	bytesOnly.TrainWithOptions(corpus, TrainOptions{VocabSize: 300})
+	chars := NewBPETokenizer()
+	chars.TrainWithOptions(corpus, opts)
+
+	if len(chars.Chars) == 0 {
+		t.Fatal("no characters selected")
+	}
+	if chars.VocabSize() > opts.VocabSize {
+		t.Errorf("VocabSize() = %d, want at most %d", chars.VocabSize(), opts.VocabSize)
+	}
+	for _, m := range chars.Merges {
+		if m.Index < 256+len(chars.Chars) {
+			t.Fatalf("merge %v reuses a character id", m)
+		}
+	}
+	if got, want := chars.Count(corpus), bytesOnly.Count(corpus); got >= want {
+		t.Errorf("Count() = %d with characters, want fewer than %d with bytes", got, want)
+	}
+
+	// unseen characters fall back to bytes
+	text := "日本語 한국어 héllo"
+	ids := chars.Encode(text)
+	if got := chars.Decode(ids); got != text {
+		t.Errorf("Decode() = %q, want %q", got, text)
+	}
+	if got := chars.Freeze().Encode(text); !reflect.DeepEqual(got, ids) {
+		t.Errorf("Freeze().Encode() = %v, want %v", got, ids)
+	}
+
+	// both model formats keep the alphabet
+	path := filepath.Join(t.TempDir(), "chars.model")
+	if err := chars.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+	fromText := NewBPETokenizer()
+	if err := fromText.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := chars.WriteBinary(&buf); err != nil {
+		t.Fatalf("WriteBinary: %v", err)
+	}
+	fromBinary := NewBPETokenizer()
+	if err := fromBinary.LoadBinary(buf.Bytes()); err != nil {
+		t.Fatalf("LoadBinary: %v", err)
+	}
+
+	for name, loaded := range map[string]*BPETokenizer{"text": fromText, "binary": fromBinary} {
+		if !reflect.DeepEqual(loaded.Chars, chars.Chars) {
+			t.Errorf("%s: Chars = %v, want %v", name, loaded.Chars, chars.Chars)
+		}
+		if loaded.VocabSize() != chars.VocabSize() {
+			t.Errorf("%s: VocabSize() = %d, want %d", name, loaded.VocabSize(), chars.VocabSize())
+		}
+		if got := loaded.Encode(text); !reflect.DeepEqual(got, ids) {
+			t.Errorf("%s: Encode() = %v, want %v", name, got, ids)
+		}
+	}
+
+	if _, err := MergeModels(chars, bytesOnly, 0); err == nil {
+		t.Error("MergeModels: expected an error for a character alphabet")
+	}
+}
@@ -0,0 +1,146 @@
+package bpe
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// BreakPoint is a kind of position where the Chunker prefers to end a window.
+type BreakPoint int
+
+const (
+	BreakParagraph BreakPoint = iota // after a blank line
+	BreakSentence                    // after '.', '!' or '?' followed by whitespace, or after a line break
+	BreakChunk                       // between two pre-tokenizer chunks
+)
+
+// Window is a slice of a document that fits in the chunker's token budget.
+// In SentencePiece dummy-prefix mode decode the IDs of a window with
+// Start > 0 with DecodeOptions.KeepDummyPrefix, its leading space is real.
+type Window struct {
+	IDs   []int
+	Text  string
+	Start int // byte offset of the window in the document
+	End   int // byte offset one past the end of the window
+}
+
+// Chunker splits documents into overlapping windows of at most Size tokens.
+// Consecutive windows share Overlap tokens. A window is ended at the last
+// break point of the first kind in Breaks that is found, falling back to the
+// next kind and finally to a plain token boundary.
+type Chunker struct {
+	Tokenizer *BPETokenizer
+	Size      int
+	Overlap   int
+	Breaks    []BreakPoint
+}
+
+func NewChunker(tokenizer *BPETokenizer, size int, overlap int) *Chunker {
+	return &Chunker{
+		Tokenizer: tokenizer,
+		Size:      size,
+		Overlap:   overlap,
+		Breaks:    []BreakPoint{BreakParagraph, BreakSentence, BreakChunk},
+	}
+}
+
+/**
+ * Split a document into windows
+ * 1. Encode the document with offsets
+ * 2. From the window start, take up to Size tokens
+ * 3. If the document continues, move the end back to the preferred break point
+ * 4. Start the next window Overlap tokens before the end
+ * Windows start and end on character boundaries, byte tokens of a character
+ * are never split between windows. Split fails when Size tokens cannot hold
+ * a whole character.
+**/
+func (c *Chunker) Split(text string) ([]Window, error) {
+	if c.Size <= 0 {
+		return nil, fmt.Errorf("chunker: size must be positive, got %d", c.Size)
+	}
+	if c.Overlap < 0 || c.Overlap >= c.Size {
+		return nil, fmt.Errorf("chunker: overlap must be in [0, %d), got %d", c.Size, c.Overlap)
+	}
+
+	tokens := c.Tokenizer.EncodeWithOffsets(text)
+	windows := []Window{}
+
+	for start := 0; start < len(tokens); {
+		end := start + c.Size
+		if end >= len(tokens) {
+			end = len(tokens)
+		} else {
+			end = c.breakBefore(text, tokens, start, end)
+			if end < 0 {
+				return nil, fmt.Errorf("chunker: size %d is too small for the character at byte %d", c.Size, tokens[start].Start)
+			}
+		}
+
+		ids := make([]int, 0, end-start)
+		for _, tok := range tokens[start:end] {
+			ids = append(ids, tok.ID)
+		}
+		windows = append(windows, Window{
+			IDs:   ids,
+			Text:  text[tokens[start].Start:tokens[end-1].End],
+			Start: tokens[start].Start,
+			End:   tokens[end-1].End,
+		})
+
+		if end == len(tokens) {
+			break
+		}
+		start = end - c.Overlap
+		for !isRuneStart(text, tokens, start) {
+			start++
+		}
+	}
+
+	return windows, nil
+}
+
+// breakBefore returns the preferred token index at or before end to stop a
+// window starting at start, -1 if no character boundary is in reach. It stays
+// past start+Overlap so windows advance.
+func (c *Chunker) breakBefore(text string, tokens []Token, start int, end int) int {
+	for _, kind := range c.Breaks {
+		for k := end; k > start+c.Overlap; k-- {
+			if isRuneStart(text, tokens, k) && isBreak(kind, text, tokens, k) {
+				return k
+			}
+		}
+	}
+	for k := end; k > start+c.Overlap; k-- {
+		if isRuneStart(text, tokens, k) {
+			return k
+		}
+	}
+	return -1
+}
+
+// isRuneStart reports whether tokens[k] starts a character, or k is the end
+func isRuneStart(text string, tokens []Token, k int) bool {
+	return k == len(tokens) || utf8.RuneStart(text[tokens[k].Start])
+}
+
+// isBreak reports whether the boundary before tokens[k] is a break of the given kind
+func isBreak(kind BreakPoint, text string, tokens []Token, k int) bool {
+	before := text[:tokens[k].Start]
+
+	switch kind {
+	case BreakParagraph:
+		return strings.HasSuffix(before, "\n\n")
+	case BreakSentence:
+		if strings.HasSuffix(before, "\n") {
+			return true
+		}
+		after := text[tokens[k].Start:]
+		return (strings.HasSuffix(before, ".") || strings.HasSuffix(before, "!") || strings.HasSuffix(before, "?")) &&
+			strings.IndexAny(after[:1], " \t\r\n") == 0
+	case BreakChunk:
+		return tokens[k].Chunk != tokens[k-1].Chunk
+	}
+
+	return false
+}
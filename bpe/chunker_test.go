@@ -0,0 +1,152 @@
+package bpe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkerSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		size     int
+		overlap  int
+		expected []string
+	}{
+		{
+			name:     "fits in one window",
+			text:     "hello",
+			size:     10,
+			expected: []string{"hello"},
+		},
+		{
+			name:     "sentence break",
+			text:     "aaaa bbbb. cccc dddd.",
+			size:     12,
+			expected: []string{"aaaa bbbb.", " cccc dddd."},
+		},
+		{
+			name:     "paragraph preferred over sentence",
+			text:     "aa.\n\nbb. cc. dd",
+			size:     12,
+			expected: []string{"aa.\n\n", "bb. cc. dd"},
+		},
+		{
+			name:     "chunk break",
+			text:     "aaaa bbbb cccc",
+			size:     7,
+			expected: []string{"aaaa", " bbbb", " cccc"},
+		},
+		{
+			name:     "hard cut with overlap",
+			text:     "abcdefghij",
+			size:     4,
+			overlap:  2,
+			expected: []string{"abcd", "cdef", "efgh", "ghij"},
+		},
+		{
+			name:     "characters are not split",
+			text:     "日本語",
+			size:     4,
+			expected: []string{"日", "本", "語"},
+		},
+		{
+			name:     "overlap starts on a character",
+			text:     "日本語",
+			size:     5,
+			overlap:  1,
+			expected: []string{"日", "本", "語"},
+		},
+		{
+			name:     "empty text",
+			text:     "",
+			size:     4,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker := NewChunker(NewBPETokenizer(), tt.size, tt.overlap)
+			windows, err := chunker.Split(tt.text)
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+
+			texts := []string{}
+			for _, w := range windows {
+				texts = append(texts, w.Text)
+			}
+			if !reflect.DeepEqual(texts, tt.expected) {
+				t.Errorf("Split() = %q, want %q", texts, tt.expected)
+			}
+		})
+	}
+}
+
+func TestChunkerWindows(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	text := "The first sentence. The second one!\n\nA new paragraph with héllo and 日本語. The end?"
+	tokenizer.Train(text)
+
+	chunker := NewChunker(tokenizer, 8, 3)
+	windows, err := chunker.Split(text)
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	if len(windows) < 2 {
+		t.Fatalf("expected several windows, got %d", len(windows))
+	}
+
+	for i, w := range windows {
+		if len(w.IDs) > chunker.Size {
+			t.Errorf("window %d has %d tokens, limit is %d", i, len(w.IDs), chunker.Size)
+		}
+		if w.Text != text[w.Start:w.End] {
+			t.Errorf("window %d text %q does not match offsets %q", i, w.Text, text[w.Start:w.End])
+		}
+		if tokenizer.Decode(w.IDs) != w.Text {
+			t.Errorf("window %d decodes to %q, want %q", i, tokenizer.Decode(w.IDs), w.Text)
+		}
+	}
+
+	if windows[0].Start != 0 || windows[len(windows)-1].End != len(text) {
+		t.Error("windows should cover the whole document")
+	}
+}
+
+func TestChunkerDummyPrefix(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.TrainWithOptions(strings.Repeat("the cat sat on the mat. ", 20), TrainOptions{
+		VocabSize:     280,
+		SentencePiece: &SentencePieceOptions{DummyPrefix: true},
+	})
+
+	text := "the cat sat on the mat. the cat sat."
+	windows, err := NewChunker(tokenizer, 3, 1).Split(text)
+	if err != nil {
+		t.Fatalf("Split() error = %v", err)
+	}
+	for i, w := range windows {
+		// only the first window starts with the dummy prefix
+		decoded, err := tokenizer.DecodeWithOptions(w.IDs, DecodeOptions{KeepDummyPrefix: w.Start > 0})
+		if err != nil || decoded != w.Text {
+			t.Errorf("window %d decodes to %q, %v, want %q", i, decoded, err, w.Text)
+		}
+	}
+}
+
+func TestChunkerInvalidConfig(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+
+	if _, err := NewChunker(tokenizer, 0, 0).Split("text"); err == nil {
+		t.Error("expected error for zero size")
+	}
+	if _, err := NewChunker(tokenizer, 4, 4).Split("text"); err == nil {
+		t.Error("expected error for overlap not smaller than size")
+	}
+	if _, err := NewChunker(tokenizer, 2, 0).Split("日本"); err == nil {
+		t.Error("expected error for a size smaller than a character")
+	}
+}
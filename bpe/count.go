@@ -0,0 +1,70 @@
+package bpe
+
+import "unicode/utf8"
+
+// Side selects which end of the text TruncateToTokens keeps.
+type Side int
+
+const (
+	Prefix Side = iota // keep the beginning of the text
+	Suffix             // keep the end of the text
+)
+
+/**
+ * Count the tokens Encode would return for text
+ * 1. Split text into pre-tokenizer chunks
+ * 2. Encode each chunk and add up the lengths, without building the full id slice
+**/
+func (bpe *BPETokenizer) Count(text string) int {
+	ranks := bpe.mergeRanks()
+
+	var buf []TokenID // reused for every chunk
+	count := 0
+	text = bpe.normalize(text)
+	for _, c := range bpe.chunks(text) {
+		buf = bpe.encodeChunk(buf[:0], text[c.start:c.end], ranks)
+		count += len(buf)
+	}
+
+	return count
+}
+
+/**
+ * Truncate text so that its encoding fits in n tokens
+ * 1. Encode text once with offsets, return it unchanged if it already fits
+ * 2. Take the end of token n (or the start of the n-th last token) as the cut
+ * 3. Re-count the rune boundaries within the longest token of the cut,
+ *    longest prefix (or suffix) first, and return the first that fits. Counts
+ *    are not monotonic, "abc" can be one token where "ab" is two, so the cut
+ *    itself is only a starting point
+ * 4. If none of them fits, keep shortening until one does, the empty text
+ *    always fits
+**/
+func (bpe *BPETokenizer) TruncateToTokens(text string, n int, side Side) string {
+	if n <= 0 || text == "" {
+		return ""
+	}
+	tokens := bpe.EncodeWithOffsets(text)
+	if len(tokens) <= n {
+		return text
+	}
+
+	window := bpe.table().maxTokenLength()
+	if side == Suffix {
+		cut := tokens[len(tokens)-n].Start
+		for start := max(cut-window, 0); start < len(text); start++ {
+			if utf8.RuneStart(text[start]) && bpe.Count(text[start:]) <= n {
+				return text[start:]
+			}
+		}
+		return ""
+	}
+
+	cut := tokens[n-1].End
+	for end := min(cut+window, len(text)); end > 0; end-- {
+		if (end == len(text) || utf8.RuneStart(text[end])) && bpe.Count(text[:end]) <= n {
+			return text[:end]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package bpe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.Train("hello world hello world\nhéllo 日本語")
+
+	texts := []string{
+		"",
+		"hello",
+		"hello world hello world",
+		"héllo 日本語\n\nworld 12345",
+	}
+
+	for _, text := range texts {
+		if got, want := tokenizer.Count(text), len(tokenizer.Encode(text)); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		merges   []Pair
+		text     string
+		n        int
+		side     Side
+		expected string
+	}{
+		{
+			name:     "fits already",
+			text:     "hello",
+			n:        10,
+			side:     Prefix,
+			expected: "hello",
+		},
+		{
+			name:     "zero tokens",
+			text:     "hello",
+			n:        0,
+			side:     Prefix,
+			expected: "",
+		},
+		{
+			name:     "ascii prefix",
+			text:     "hello world",
+			n:        3,
+			side:     Prefix,
+			expected: "hel",
+		},
+		{
+			name:     "ascii suffix",
+			text:     "hello world",
+			n:        3,
+			side:     Suffix,
+			expected: "rld",
+		},
+		{
+			name:     "merged tokens",
+			merges:   []Pair{{'h', 'e'}, {256, 'l'}},
+			text:     "hello hello",
+			n:        3,
+			side:     Prefix,
+			expected: "hello",
+		},
+		{
+			name:     "prefix does not split a character",
+			text:     "日本",
+			n:        4,
+			side:     Prefix,
+			expected: "日",
+		},
+		{
+			name:     "suffix does not split a character",
+			text:     "日本",
+			n:        5,
+			side:     Suffix,
+			expected: "本",
+		},
+		{
+			// "a" = 1, "ab" = 2, "abc" = 1 token, longer is not always more
+			name:     "non-monotonic prefix",
+			merges:   []Pair{{'b', 'c'}, {'a', 256}},
+			text:     "abcdddd",
+			n:        1,
+			side:     Prefix,
+			expected: "abc",
+		},
+		{
+			// "b" = 1, "ab" = 2, "cab" = 1 token
+			name:     "non-monotonic suffix",
+			merges:   []Pair{{'c', 'a'}, {256, 'b'}},
+			text:     "ddddcab",
+			n:        1,
+			side:     Suffix,
+			expected: "cab",
+		},
+		{
+			name:     "no whole character fits",
+			text:     "日本",
+			n:        2,
+			side:     Prefix,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := newTokenizerWithMerges(tt.merges...)
+			result := tokenizer.TruncateToTokens(tt.text, tt.n, tt.side)
+			if result != tt.expected {
+				t.Errorf("TruncateToTokens() = %q, want %q", result, tt.expected)
+			}
+			if count := tokenizer.Count(result); count > tt.n {
+				t.Errorf("truncated text has %d tokens, limit is %d", count, tt.n)
+			}
+		})
+	}
+}
+
+func TestTruncateToTokensDummyPrefix(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.TrainWithOptions(strings.Repeat("hello world ", 20), TrainOptions{
+		VocabSize:     270,
+		SentencePiece: &SentencePieceOptions{DummyPrefix: true},
+	})
+
+	// "world" is encoded as the single token " world", but "o world" is not
+	if got := tokenizer.TruncateToTokens("hello world", 1, Suffix); got != "world" {
+		t.Errorf("TruncateToTokens(Suffix) = %q, want %q", got, "world")
+	}
+	if got := tokenizer.TruncateToTokens("hello world", 1, Prefix); got != "hello" {
+		t.Errorf("TruncateToTokens(Prefix) = %q, want %q", got, "hello")
+	}
+}
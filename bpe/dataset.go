@@ -0,0 +1,244 @@
+package bpe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"runtime"
+)
+
+// DatasetOptions configures WriteDataset.
+type DatasetOptions struct {
+	Prefix       string // shards are written to <Prefix>_0000.bin, ... and the index to <Prefix>.json
+	ShardSize    int    // maximum tokens per shard, 0 writes a single shard
+	Separator    int    // id written after every document when UseSeparator is set
+	UseSeparator bool   // usually the separator is an id past the vocabulary, like GPT-2's <|endoftext|>
+	Workers      int    // number of files encoded in parallel, 0 uses GOMAXPROCS
+
+	// Dropout encodes with BPE-dropout when > 0, see EncodeWithDropout. Every
+	// file gets its own generator seeded with Seed and its position, so the
+	// shards do not depend on Workers.
+	Dropout float64
+	Seed    uint64
+}
+
+// DatasetIndex describes the shards and document boundaries of a dataset.
+// Document offsets count tokens across all shards, so a document starting
+// at Offset lives in shard Offset/ShardSize when ShardSize is set.
+type DatasetIndex struct {
+	Dtype     string            `json:"dtype"`
+	ShardSize int               `json:"shard_size"`
+	Separator int               `json:"separator"` // -1 when documents are not separated
+	Shards    []DatasetShard    `json:"shards"`
+	Documents []DatasetDocument `json:"documents"`
+}
+
+type DatasetShard struct {
+	File   string `json:"file"`
+	Tokens int    `json:"tokens"`
+}
+
+type DatasetDocument struct {
+	Source string `json:"source"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"` // tokens, not counting the separator
+}
+
+type encodedFile struct {
+	tokens []int
+	err    error
+}
+
+/**
+ * Encode files into flat little-endian token shards
+ * 1. Pick uint16 if every id (and the separator) is below 65536, else uint32
+ * 2. Encode up to Workers files in parallel with a frozen Encoder, the
+ *    tokenizer itself is not safe for concurrent use
+ * 3. Write the encoded files to the shards in input order, followed by the separator
+ * 4. Start a new shard whenever the current one holds ShardSize tokens
+ * 5. Write the index of shards and document boundaries
+**/
+func (bpe *BPETokenizer) WriteDataset(files []string, opts DatasetOptions) (*DatasetIndex, error) {
+	if opts.Prefix == "" {
+		return nil, fmt.Errorf("dataset: empty output prefix")
+	}
+	if opts.ShardSize < 0 {
+		return nil, fmt.Errorf("dataset: shard size must not be negative, got %d", opts.ShardSize)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	separator := -1
+	if opts.UseSeparator {
+		if opts.Separator < 0 {
+			return nil, fmt.Errorf("dataset: invalid separator id %d", opts.Separator)
+		}
+		separator = opts.Separator
+	}
+
+	maxID := separator
+	for _, c := range bpe.Chars {
+		maxID = max(maxID, c.Index)
+	}
+	for _, m := range bpe.Merges {
+		maxID = max(maxID, m.Index)
+	}
+	width := 2
+	index := &DatasetIndex{
+		Dtype:     "uint16",
+		ShardSize: opts.ShardSize,
+		Separator: separator,
+		Shards:    []DatasetShard{},
+		Documents: []DatasetDocument{},
+	}
+	if maxID >= 1<<16 {
+		width = 4
+		index.Dtype = "uint32"
+	}
+
+	encoder := bpe.Freeze()
+
+	// encode in parallel, but keep at most `workers` results waiting for the writer
+	results := make([]chan encodedFile, len(files))
+	for i := range results {
+		results[i] = make(chan encodedFile, 1)
+	}
+	slots := make(chan struct{}, workers)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for i, name := range files {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func(i int, name string) {
+				data, err := os.ReadFile(name)
+				if err != nil {
+					results[i] <- encodedFile{err: err}
+					return
+				}
+				if opts.Dropout > 0 {
+					rng := rand.New(rand.NewPCG(opts.Seed, uint64(i)))
+					results[i] <- encodedFile{tokens: encoder.EncodeWithDropout(string(data), opts.Dropout, rng)}
+					return
+				}
+				results[i] <- encodedFile{tokens: encoder.Encode(string(data))}
+			}(i, name)
+		}
+	}()
+
+	w := &shardWriter{prefix: opts.Prefix, shardSize: opts.ShardSize, width: width, index: index}
+	offset := 0
+	for i, name := range files {
+		result := <-results[i]
+		<-slots
+		if result.err != nil {
+			w.close()
+			return nil, fmt.Errorf("dataset: %w", result.err)
+		}
+
+		tokens := result.tokens
+		if separator >= 0 {
+			tokens = append(tokens, separator)
+		}
+		if err := w.write(tokens); err != nil {
+			w.close()
+			return nil, err
+		}
+
+		length := len(result.tokens)
+		index.Documents = append(index.Documents, DatasetDocument{Source: name, Offset: offset, Length: length})
+		offset += len(tokens)
+	}
+
+	if err := w.close(); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("dataset: %w", err)
+	}
+	if err := os.WriteFile(opts.Prefix+".json", data, 0o644); err != nil {
+		return nil, fmt.Errorf("dataset: %w", err)
+	}
+
+	return index, nil
+}
+
+// shardWriter streams tokens into consecutive shard files
+type shardWriter struct {
+	prefix    string
+	shardSize int
+	width     int
+	index     *DatasetIndex
+
+	file *os.File
+	buf  *bufio.Writer
+}
+
+func (w *shardWriter) write(tokens []int) error {
+	var scratch [4]byte
+
+	for _, id := range tokens {
+		if w.file == nil || (w.shardSize > 0 && w.index.Shards[len(w.index.Shards)-1].Tokens == w.shardSize) {
+			if err := w.next(); err != nil {
+				return err
+			}
+		}
+
+		if w.width == 2 {
+			binary.LittleEndian.PutUint16(scratch[:], uint16(id))
+		} else {
+			binary.LittleEndian.PutUint32(scratch[:], uint32(id))
+		}
+		if _, err := w.buf.Write(scratch[:w.width]); err != nil {
+			return fmt.Errorf("dataset: %w", err)
+		}
+		w.index.Shards[len(w.index.Shards)-1].Tokens++
+	}
+
+	return nil
+}
+
+func (w *shardWriter) next() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%04d.bin", w.prefix, len(w.index.Shards))
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("dataset: %w", err)
+	}
+
+	w.file = file
+	w.buf = bufio.NewWriter(file)
+	w.index.Shards = append(w.index.Shards, DatasetShard{File: name})
+	return nil
+}
+
+func (w *shardWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.buf.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("dataset: %w", err)
+	}
+	return nil
+}
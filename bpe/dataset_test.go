@@ -0,0 +1,137 @@
+package bpe
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readShards(t *testing.T, index *DatasetIndex) []int {
+	t.Helper()
+
+	var ids []int
+	for _, shard := range index.Shards {
+		data, err := os.ReadFile(shard.File)
+		if err != nil {
+			t.Fatalf("reading shard: %v", err)
+		}
+		width := 2
+		if index.Dtype == "uint32" {
+			width = 4
+		}
+		if len(data) != shard.Tokens*width {
+			t.Errorf("shard %s has %d bytes, want %d", shard.File, len(data), shard.Tokens*width)
+		}
+		for i := 0; i+width <= len(data); i += width {
+			if width == 2 {
+				ids = append(ids, int(binary.LittleEndian.Uint16(data[i:])))
+			} else {
+				ids = append(ids, int(binary.LittleEndian.Uint32(data[i:])))
+			}
+		}
+	}
+	return ids
+}
+
+func TestWriteDataset(t *testing.T) {
+	dir := t.TempDir()
+	texts := []string{"hello world", "hi\nthere", "héllo"}
+	var files []string
+	for i, text := range texts {
+		name := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+
+	tokenizer := newTokenizerWithMerges(Pair{'h', 'e'}, Pair{'l', 'l'})
+	prefix := filepath.Join(dir, "train")
+	index, err := tokenizer.WriteDataset(files, DatasetOptions{
+		Prefix:       prefix,
+		ShardSize:    5,
+		Separator:    258,
+		UseSeparator: true,
+		Workers:      2,
+	})
+	if err != nil {
+		t.Fatalf("WriteDataset() error = %v", err)
+	}
+
+	if index.Dtype != "uint16" {
+		t.Errorf("Dtype = %s, want uint16", index.Dtype)
+	}
+
+	var expected []int
+	for i, text := range texts {
+		ids := tokenizer.Encode(text)
+		doc := index.Documents[i]
+		if doc.Source != files[i] || doc.Offset != len(expected) || doc.Length != len(ids) {
+			t.Errorf("document %d = %+v, want offset %d length %d", i, doc, len(expected), len(ids))
+		}
+		expected = append(expected, ids...)
+		expected = append(expected, 258)
+	}
+
+	for i, shard := range index.Shards {
+		if shard.Tokens > 5 {
+			t.Errorf("shard %d has %d tokens, limit is 5", i, shard.Tokens)
+		}
+	}
+
+	if ids := readShards(t, index); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("shards hold %v, want %v", ids, expected)
+	}
+
+	data, err := os.ReadFile(prefix + ".json")
+	if err != nil {
+		t.Fatalf("reading index: %v", err)
+	}
+	var saved DatasetIndex
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("parsing index: %v", err)
+	}
+	if !reflect.DeepEqual(&saved, index) {
+		t.Errorf("saved index %+v differs from returned %+v", saved, *index)
+	}
+}
+
+func TestWriteDatasetUint32(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "doc.txt")
+	if err := os.WriteFile(name, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := NewBPETokenizer().WriteDataset([]string{name}, DatasetOptions{
+		Prefix:       filepath.Join(dir, "out"),
+		Separator:    70000,
+		UseSeparator: true,
+	})
+	if err != nil {
+		t.Fatalf("WriteDataset() error = %v", err)
+	}
+
+	if index.Dtype != "uint32" {
+		t.Errorf("Dtype = %s, want uint32", index.Dtype)
+	}
+	if len(index.Shards) != 1 {
+		t.Errorf("expected a single shard, got %d", len(index.Shards))
+	}
+	if ids := readShards(t, index); !reflect.DeepEqual(ids, []int{'a', 'b', 'c', 70000}) {
+		t.Errorf("shards hold %v", ids)
+	}
+}
+
+func TestWriteDatasetMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewBPETokenizer().WriteDataset([]string{filepath.Join(dir, "missing.txt")}, DatasetOptions{
+		Prefix: filepath.Join(dir, "out"),
+	})
+	if err == nil {
+		t.Error("expected error for missing input file")
+	}
+}
@@ -0,0 +1,110 @@
+package bpe
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// UTF8Policy decides what decoding does with bytes that are not valid UTF-8,
+// which happens when a multi-byte character is split across tokens.
+type UTF8Policy int
+
+const (
+	UTF8Keep    UTF8Policy = iota // keep invalid bytes as they are
+	UTF8Replace                   // replace each invalid byte with U+FFFD
+	UTF8Escape                    // write each invalid byte as \xNN
+)
+
+func (p UTF8Policy) String() string {
+	switch p {
+	case UTF8Keep:
+		return "keep"
+	case UTF8Replace:
+		return "replace"
+	case UTF8Escape:
+		return "escape"
+	}
+	return fmt.Sprintf("UTF8Policy(%d)", int(p))
+}
+
+// ParseUTF8Policy parses "keep", "replace" or "escape".
+func ParseUTF8Policy(s string) (UTF8Policy, error) {
+	for _, p := range []UTF8Policy{UTF8Keep, UTF8Replace, UTF8Escape} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return UTF8Keep, fmt.Errorf("unknown UTF-8 policy %q (want keep, replace or escape)", s)
+}
+
+// DecodeOptions configures DecodeWithOptions.
+type DecodeOptions struct {
+	Strict bool // fail with an *UnknownTokenError instead of skipping unknown ids
+	UTF8   UTF8Policy
+
+	// KeepDummyPrefix keeps a leading space in SentencePiece dummy-prefix
+	// mode. Set it when the ids do not start at the beginning of the text,
+	// e.g. a Chunker window past the first, where the space is real.
+	KeepDummyPrefix bool
+}
+
+// DecodeWithOptions decodes tokens into text using the given strictness and
+// UTF-8 policy. With the zero DecodeOptions it behaves like Decode.
+func (bpe *BPETokenizer) DecodeWithOptions(tokens []int, opts DecodeOptions) (string, error) {
+	return decodeWithOptions(tokens, bpe.table(), bpe.SentencePiece, opts)
+}
+
+// DecodeWithOptions decodes tokens into text, see BPETokenizer.DecodeWithOptions
+func (e *Encoder) DecodeWithOptions(tokens []int, opts DecodeOptions) (string, error) {
+	return decodeWithOptions(tokens, e.table, e.tokenizer.SentencePiece, opts)
+}
+
+/**
+ * Decode with options
+ * 1. Look up the bytes of each token, skipping or failing on unknown ids
+ * 2. Drop the SentencePiece dummy prefix, unless KeepDummyPrefix is set
+ * 3. Apply the UTF-8 policy to the decoded bytes
+**/
+func decodeWithOptions(tokens []int, table *vocabulary, spm *SentencePieceOptions, opts DecodeOptions) (string, error) {
+	var raw []byte
+	for i, token := range tokens {
+		bytes := table.token(token)
+		if bytes == nil {
+			if opts.Strict {
+				return "", &UnknownTokenError{ID: token, Position: i}
+			}
+			continue
+		}
+		raw = append(raw, bytes...)
+	}
+
+	if !opts.KeepDummyPrefix {
+		raw = trimDummyPrefix(raw, spm)
+	}
+	return ApplyUTF8Policy(raw, opts.UTF8), nil
+}
+
+// ApplyUTF8Policy converts decoded bytes to a string, handling bytes that
+// are not valid UTF-8 according to policy.
+func ApplyUTF8Policy(raw []byte, policy UTF8Policy) string {
+	if policy == UTF8Keep || utf8.Valid(raw) {
+		return string(raw)
+	}
+
+	result := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); {
+		r, size := utf8.DecodeRune(raw[i:])
+		if r == utf8.RuneError && size == 1 {
+			if policy == UTF8Escape {
+				result = fmt.Appendf(result, "\\x%02x", raw[i])
+			} else {
+				result = utf8.AppendRune(result, utf8.RuneError)
+			}
+		} else {
+			result = append(result, raw[i:i+size]...)
+		}
+		i += size
+	}
+
+	return string(result)
+}
@@ -0,0 +1,84 @@
+package bpe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeWithOptions(t *testing.T) {
+	// "é" is 0xC3 0xA9, the merge joins 'a' with its first byte only
+	tokenizer := newTokenizerWithMerges(Pair{'a', 0xC3})
+
+	tests := []struct {
+		name     string
+		tokens   []int
+		opts     DecodeOptions
+		expected string
+		err      error
+	}{
+		{
+			name:     "valid text",
+			tokens:   []int{256, 0xA9},
+			opts:     DecodeOptions{UTF8: UTF8Escape},
+			expected: "aé",
+		},
+		{
+			name:     "keep invalid bytes",
+			tokens:   []int{256},
+			expected: "a\xc3",
+		},
+		{
+			name:     "replace invalid bytes",
+			tokens:   []int{256, 'b', 0xA9},
+			opts:     DecodeOptions{UTF8: UTF8Replace},
+			expected: "a�b�",
+		},
+		{
+			name:     "escape invalid bytes",
+			tokens:   []int{256, 'b', 0xA9},
+			opts:     DecodeOptions{UTF8: UTF8Escape},
+			expected: `a\xc3b\xa9`,
+		},
+		{
+			name:     "skip unknown ids",
+			tokens:   []int{'a', 999, 'b'},
+			expected: "ab",
+		},
+		{
+			name:   "strict unknown id",
+			tokens: []int{'a', 'b', 999},
+			opts:   DecodeOptions{Strict: true},
+			err:    &UnknownTokenError{ID: 999, Position: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tokenizer.DecodeWithOptions(tt.tokens, tt.opts)
+			if !reflect.DeepEqual(err, tt.err) {
+				t.Fatalf("DecodeWithOptions() error = %v, want %v", err, tt.err)
+			}
+			if result != tt.expected {
+				t.Errorf("DecodeWithOptions() = %q, want %q", result, tt.expected)
+			}
+
+			encResult, encErr := tokenizer.Freeze().DecodeWithOptions(tt.tokens, tt.opts)
+			if encResult != result || !reflect.DeepEqual(encErr, err) {
+				t.Errorf("Encoder.DecodeWithOptions() = %q, %v, want %q, %v", encResult, encErr, result, err)
+			}
+		})
+	}
+}
+
+func TestParseUTF8Policy(t *testing.T) {
+	for _, p := range []UTF8Policy{UTF8Keep, UTF8Replace, UTF8Escape} {
+		parsed, err := ParseUTF8Policy(p.String())
+		if err != nil || parsed != p {
+			t.Errorf("ParseUTF8Policy(%q) = %v, %v, want %v", p.String(), parsed, err, p)
+		}
+	}
+
+	if _, err := ParseUTF8Policy("ignore"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,60 @@
+package bpe
+
+import "math/rand/v2"
+
+/**
+ * Encode text with BPE-dropout (Provilkov et al., 2020), giving a different
+ * segmentation of the same text on every call as training-time regularization
+ * 1. Split text into chunks like Encode
+ * 2. In every step, drop each candidate merge of the chunk with probability p
+ * 3. Apply the remaining candidate with the lowest rank in Merges, the
+ *    leftmost one on ties, and stop when every candidate was dropped
+ * With p = 0 this is Encode, with p = 1 every chunk stays in base tokens.
+ * Pass rand.New(rand.NewPCG(seed, stream)) to make the output reproducible.
+**/
+func (bpe *BPETokenizer) EncodeWithDropout(text string, p float64, rng *rand.Rand) []int {
+	return toInts(bpe.appendEncodeWithDropout(nil, text, bpe.mergeRanks(), p, rng))
+}
+
+// EncodeWithDropout encodes text with BPE-dropout, bypassing the chunk cache,
+// see BPETokenizer.EncodeWithDropout
+func (e *Encoder) EncodeWithDropout(text string, p float64, rng *rand.Rand) []int {
+	return toInts(e.tokenizer.appendEncodeWithDropout(nil, text, e.ranks, p, rng))
+}
+
+func (bpe *BPETokenizer) appendEncodeWithDropout(dst []TokenID, text string, ranks map[Pair]int, p float64, rng *rand.Rand) []TokenID {
+	text = bpe.normalize(text)
+	for _, c := range bpe.chunks(text) {
+		dst = bpe.encodeChunkWithDropout(dst, text[c.start:c.end], ranks, p, rng)
+	}
+	return dst
+}
+
+// encodeChunkWithDropout is encodeChunk merging one candidate at a time, so
+// every occurrence of a pair can be dropped on its own
+func (bpe *BPETokenizer) encodeChunkWithDropout(dst []TokenID, text string, ranks map[Pair]int, p float64, rng *rand.Rand) []TokenID {
+	start := len(dst)
+	dst = appendBaseTokens(dst, text, bpe.charIDs())
+
+	tokens := dst[start:]
+	for len(tokens) >= 2 {
+		best, at := -1, -1
+		for i := 0; i < len(tokens)-1; i++ {
+			rank, exists := ranks[Pair{int(tokens[i]), int(tokens[i+1])}]
+			if !exists || rng.Float64() < p {
+				continue
+			}
+			if best == -1 || rank < best {
+				best, at = rank, i
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		tokens[at] = TokenID(bpe.Merges[best].Index)
+		tokens = append(tokens[:at+1], tokens[at+2:]...)
+	}
+
+	return dst[:start+len(tokens)]
+}
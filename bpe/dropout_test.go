@@ -0,0 +1,99 @@
+package bpe
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeWithDropout(t *testing.T) {
+	corpus := strings.Repeat("the quick brown fox jumps over the lazy dog\nhello héllo 日本語\n", 10)
+	tokenizer := NewBPETokenizer()
+	tokenizer.TrainWithOptions(corpus, TrainOptions{VocabSize: 320})
+	text := "the lazy fox says hello 日本語"
+	rng := rand.New(rand.NewPCG(1, 0))
+
+	if got, want := tokenizer.EncodeWithDropout(text, 0, rng), tokenizer.Encode(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeWithDropout(p=0) = %v, want Encode() %v", got, want)
+	}
+
+	var raw []int
+	for i := 0; i < len(text); i++ {
+		raw = append(raw, int(text[i]))
+	}
+	if got := tokenizer.EncodeWithDropout(text, 1, rng); !reflect.DeepEqual(got, raw) {
+		t.Errorf("EncodeWithDropout(p=1) = %v, want raw bytes %v", got, raw)
+	}
+
+	// the same seed gives the same segmentation, every segmentation decodes to text
+	first := tokenizer.EncodeWithDropout(text, 0.5, rand.New(rand.NewPCG(7, 0)))
+	if got := tokenizer.EncodeWithDropout(text, 0.5, rand.New(rand.NewPCG(7, 0))); !reflect.DeepEqual(got, first) {
+		t.Errorf("same seed: %v, then %v", first, got)
+	}
+	encoder := tokenizer.Freeze()
+	if got := encoder.EncodeWithDropout(text, 0.5, rand.New(rand.NewPCG(7, 0))); !reflect.DeepEqual(got, first) {
+		t.Errorf("Freeze().EncodeWithDropout() = %v, want %v", got, first)
+	}
+	segmentations := make(map[string]bool)
+	for seed := uint64(0); seed < 20; seed++ {
+		ids := tokenizer.EncodeWithDropout(text, 0.5, rand.New(rand.NewPCG(seed, 0)))
+		if got := tokenizer.Decode(ids); got != text {
+			t.Fatalf("Decode(EncodeWithDropout()) = %q, want %q", got, text)
+		}
+		if len(ids) > len(raw) {
+			t.Errorf("EncodeWithDropout() has %d tokens, more than the %d bytes", len(ids), len(raw))
+		}
+		segmentations[fmt.Sprint(ids)] = true
+	}
+	if len(segmentations) < 2 {
+		t.Error("EncodeWithDropout(p=0.5) gave the same segmentation for every seed")
+	}
+}
+
+func TestEncodeWithDropoutCharacters(t *testing.T) {
+	corpus := strings.Repeat("東京は日本の首都です。\n", 10)
+	tokenizer := NewBPETokenizer()
+	tokenizer.TrainWithOptions(corpus, TrainOptions{VocabSize: 300, CharacterCoverage: 1})
+	text := "日本の首都"
+
+	// with a character alphabet p=1 leaves the base characters
+	want := toInts(appendBaseTokens(nil, text, tokenizer.charIDs()))
+	if got := tokenizer.EncodeWithDropout(text, 1, rand.New(rand.NewPCG(1, 0))); !reflect.DeepEqual(got, want) {
+		t.Errorf("EncodeWithDropout(p=1) = %v, want %v", got, want)
+	}
+}
+
+func TestWriteDatasetDropout(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i, text := range []string{"hello hello hello", "he llo hell", "hello"} {
+		name := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+	tokenizer := newTokenizerWithMerges(Pair{'h', 'e'}, Pair{'l', 'l'}, Pair{256, 257}, Pair{258, 'o'})
+
+	// the output only depends on the seed, not on how files are spread over workers
+	var shards [][]int
+	for _, workers := range []int{1, 3} {
+		index, err := tokenizer.WriteDataset(files, DatasetOptions{
+			Prefix:  filepath.Join(dir, "train"),
+			Workers: workers,
+			Dropout: 0.5,
+			Seed:    3,
+		})
+		if err != nil {
+			t.Fatalf("WriteDataset() error = %v", err)
+		}
+		shards = append(shards, readShards(t, index))
+	}
+	if !reflect.DeepEqual(shards[0], shards[1]) {
+		t.Errorf("shards with 1 worker %v differ from 3 workers %v", shards[0], shards[1])
+	}
+}
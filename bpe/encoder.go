@@ -0,0 +1,155 @@
+package bpe
+
+import "slices"
+
+// Encoder is an immutable snapshot of a trained BPETokenizer. Unlike the
+// tokenizer it is safe for concurrent use by multiple goroutines, and later
+// calls to Train or Load on the tokenizer do not affect it.
+type Encoder struct {
+	tokenizer *BPETokenizer // private copy, never mutated
+	ranks     map[Pair]int  // pair -> rank, built once
+	table     *vocabulary   // id -> token bytes, built once
+	cache     *chunkCache   // chunk -> ids, nil when disabled
+}
+
+// Freeze the tokenizer into an Encoder caching up to DEFAULT_CACHE_SIZE chunks
+func (bpe *BPETokenizer) Freeze() *Encoder {
+	return bpe.FreezeWithCache(DEFAULT_CACHE_SIZE)
+}
+
+/**
+ * Freeze the tokenizer into an Encoder caching up to cacheSize chunks
+ * 1. Copy Chars and Merges so the snapshot shares no mutable state, the vocabulary is
+ *    never modified once built so it is shared
+ * 2. Build the merge ranks of the copy
+ * 3. Create the chunk cache, disabled when cacheSize <= 0 or for CrossChunks
+ *    models, whose only chunk is the whole text
+**/
+func (bpe *BPETokenizer) FreezeWithCache(cacheSize int) *Encoder {
+	tokenizer := &BPETokenizer{
+		Chars:       slices.Clone(bpe.Chars),
+		Merges:      slices.Clone(bpe.Merges),
+		vocabSize:   bpe.vocabSize,
+		vocab:       bpe.table(),
+		vocabChars:  len(bpe.Chars),
+		vocabMerges: len(bpe.Merges),
+		CrossChunks: bpe.CrossChunks,
+	}
+	if bpe.SentencePiece != nil {
+		spm := *bpe.SentencePiece
+		tokenizer.SentencePiece = &spm
+	}
+
+	encoder := &Encoder{
+		tokenizer: tokenizer,
+		ranks:     tokenizer.mergeRanks(),
+		table:     tokenizer.vocab,
+	}
+	if cacheSize > 0 && !bpe.CrossChunks {
+		encoder.cache = newChunkCache(cacheSize)
+	}
+	return encoder
+}
+
+// encodeChunk appends the tokens of one pre-tokenizer chunk to dst, going
+// through the cache
+func (e *Encoder) encodeChunk(dst []TokenID, chunk string) []TokenID {
+	if e.cache == nil {
+		return e.tokenizer.encodeChunk(dst, chunk, e.ranks)
+	}
+	if ids, ok := e.cache.get(chunk); ok {
+		return append(dst, ids...)
+	}
+	start := len(dst)
+	dst = e.tokenizer.encodeChunk(dst, chunk, e.ranks)
+	e.cache.add(chunk, slices.Clone(dst[start:]))
+	return dst
+}
+
+// CacheStats returns the chunk cache counters, all zero when caching is disabled
+func (e *Encoder) CacheStats() CacheStats {
+	if e.cache == nil {
+		return CacheStats{}
+	}
+	return e.cache.stats()
+}
+
+// Encode text into tokens, see BPETokenizer.Encode
+func (e *Encoder) Encode(text string) []int {
+	return toInts(e.AppendEncode(nil, text))
+}
+
+// EncodeIDs encodes text into TokenIDs, see BPETokenizer.EncodeIDs
+func (e *Encoder) EncodeIDs(text string) []TokenID {
+	return e.AppendEncode(nil, text)
+}
+
+// AppendEncode appends the tokens of text to dst, see BPETokenizer.AppendEncode
+func (e *Encoder) AppendEncode(dst []TokenID, text string) []TokenID {
+	text = e.tokenizer.normalize(text)
+	for _, c := range e.tokenizer.chunks(text) {
+		dst = e.encodeChunk(dst, text[c.start:c.end])
+	}
+	return dst
+}
+
+// Count the tokens Encode would return for text, see BPETokenizer.Count
+func (e *Encoder) Count(text string) int {
+	var buf []TokenID // reused for every chunk
+	count := 0
+	text = e.tokenizer.normalize(text)
+	for _, c := range e.tokenizer.chunks(text) {
+		buf = e.encodeChunk(buf[:0], text[c.start:c.end])
+		count += len(buf)
+	}
+	return count
+}
+
+// Decode tokens into text, see BPETokenizer.Decode
+func (e *Encoder) Decode(tokens []int) string {
+	var result []byte
+	for _, token := range tokens {
+		result = append(result, e.table.token(token)...)
+	}
+	return string(trimDummyPrefix(result, e.tokenizer.SentencePiece))
+}
+
+// DecodeIDs decodes TokenIDs into text, see BPETokenizer.DecodeIDs
+func (e *Encoder) DecodeIDs(tokens []TokenID) string {
+	var result []byte
+	for _, token := range tokens {
+		result = append(result, e.table.token(int(token))...)
+	}
+	return string(trimDummyPrefix(result, e.tokenizer.SentencePiece))
+}
+
+// DecodeBytes decodes tokens into raw bytes, see BPETokenizer.DecodeBytes
+func (e *Encoder) DecodeBytes(tokens []int) ([]byte, error) {
+	decoded, err := appendDecode(nil, tokens, e.table)
+	return trimDummyPrefix(decoded, e.tokenizer.SentencePiece), err
+}
+
+// AppendDecode appends the bytes of tokens to dst, see BPETokenizer.AppendDecode
+func (e *Encoder) AppendDecode(dst []byte, tokens []int) ([]byte, error) {
+	return appendDecode(dst, tokens, e.table)
+}
+
+// TokenBytes returns a copy of the raw bytes of id, see BPETokenizer.TokenBytes
+func (e *Encoder) TokenBytes(id int) []byte {
+	return slices.Clone(e.table.token(id))
+}
+
+// SentencePiece returns a copy of the SentencePiece options, nil when the
+// encoder uses GPT4_SPLIT_PATTERN pre-tokenization
+func (e *Encoder) SentencePiece() *SentencePieceOptions {
+	if e.tokenizer.SentencePiece == nil {
+		return nil
+	}
+	opts := *e.tokenizer.SentencePiece
+	return &opts
+}
+
+// Piece returns the token of id as text, see BPETokenizer.Piece
+func (e *Encoder) Piece(id int) string {
+	return e.tokenizer.Piece(id)
+}
@@ -0,0 +1,72 @@
+package bpe
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	text := "hello world hello world\nhéllo 日本語"
+	tokenizer.Train(text)
+
+	encoder := tokenizer.Freeze()
+
+	ids := tokenizer.Encode(text)
+	if got := encoder.Encode(text); !reflect.DeepEqual(got, ids) {
+		t.Errorf("Encoder.Encode() = %v, want %v", got, ids)
+	}
+	if got := encoder.Count(text); got != len(ids) {
+		t.Errorf("Encoder.Count() = %d, want %d", got, len(ids))
+	}
+	if got := encoder.Decode(ids); got != text {
+		t.Errorf("Encoder.Decode() = %q, want %q", got, text)
+	}
+
+	// the snapshot must not change when the tokenizer does
+	tokenizer.Merges = tokenizer.Merges[:0]
+	if got := encoder.Encode(text); !reflect.DeepEqual(got, ids) {
+		t.Errorf("Encoder.Encode() after reset = %v, want %v", got, ids)
+	}
+}
+
+// Run with -race to check that an Encoder can be shared between goroutines
+func TestEncoderConcurrentUse(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.Train("the quick brown fox jumps over the lazy dog, the end")
+	encoder := tokenizer.Freeze()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				text := fmt.Sprintf("the quick fox %d jumps over\nthe lazy dog %d", g, i)
+				if got := encoder.Decode(encoder.Encode(text)); got != text {
+					errs <- fmt.Errorf("goroutine %d: round trip = %q, want %q", g, got, text)
+					return
+				}
+			}
+		}(g)
+	}
+
+	// retraining the source tokenizer must not race with the snapshot
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fresh := NewBPETokenizer()
+		fresh.Train("another corpus entirely")
+		*tokenizer = *fresh
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
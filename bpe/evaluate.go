@@ -0,0 +1,169 @@
+package bpe
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scripts reported by Evaluate, in display order
+var SCRIPTS = []string{"latin", "cyrillic", "cjk", "code", "other"}
+
+// ScriptStats holds compression metrics for the lines of one script.
+type ScriptStats struct {
+	Lines         int     `json:"lines"`
+	Bytes         int     `json:"bytes"`
+	Chars         int     `json:"chars"`
+	Words         int     `json:"words"`
+	Tokens        int     `json:"tokens"`
+	BytesPerToken float64 `json:"bytes_per_token"`
+	CharsPerToken float64 `json:"chars_per_token"`
+	WordsPerToken float64 `json:"words_per_token"`
+	Fertility     float64 `json:"fertility"`      // tokens per word
+	OptimalTokens int     `json:"optimal_tokens"` // tokens of EncodeOptimal
+	OptimalSaving float64 `json:"optimal_saving"` // percentage of Tokens EncodeOptimal saves
+}
+
+// Evaluation holds compression and vocabulary usage metrics of a model on a corpus.
+type Evaluation struct {
+	ScriptStats                         // totals over the whole corpus
+	VocabSize   int                     `json:"vocab_size"`
+	UsedTokens  int                     `json:"used_tokens"`
+	VocabUsed   float64                 `json:"vocab_used"`  // percentage of the vocabulary produced at least once
+	DeadTokens  []int                   `json:"dead_tokens"` // ids in the vocabulary never produced
+	Scripts     map[string]*ScriptStats `json:"scripts"`
+}
+
+/**
+ * Evaluate the tokenizer on a corpus
+ * 1. Encode the corpus and count bytes, characters, words and tokens, and
+ *    the tokens of EncodeOptimal for comparison
+ * 2. Attribute every line, with its tokens, to a script
+ * 3. Record which vocabulary ids were produced
+ * 4. Derive the ratios and the dead tokens
+**/
+func (bpe *BPETokenizer) Evaluate(corpus string) *Evaluation {
+	eval := &Evaluation{Scripts: make(map[string]*ScriptStats)}
+
+	ranks := bpe.mergeRanks()
+	trie := bpe.table().trie()
+	used := make(map[TokenID]bool)
+	var ids []TokenID
+
+	for _, line := range strings.SplitAfter(corpus, "\n") {
+		if line == "" {
+			continue
+		}
+		script := classifyLine(line)
+		stats := eval.Scripts[script]
+		if stats == nil {
+			stats = &ScriptStats{}
+			eval.Scripts[script] = stats
+		}
+
+		tokens, optimal := 0, 0
+		normalized := bpe.normalize(line)
+		for _, c := range bpe.chunks(normalized) {
+			ids = bpe.encodeChunk(ids[:0], normalized[c.start:c.end], ranks)
+			for _, id := range ids {
+				used[id] = true
+			}
+			tokens += len(ids)
+			optimal += len(appendOptimalChunk(ids[:0], normalized[c.start:c.end], trie))
+		}
+
+		for _, s := range []*ScriptStats{stats, &eval.ScriptStats} {
+			s.Lines++
+			s.Bytes += len(line)
+			s.Chars += utf8.RuneCountInString(line)
+			s.Words += len(strings.Fields(line))
+			s.Tokens += tokens
+			s.OptimalTokens += optimal
+		}
+	}
+
+	eval.ScriptStats.ratios()
+	for _, stats := range eval.Scripts {
+		stats.ratios()
+	}
+
+	table := bpe.table()
+	eval.DeadTokens = []int{}
+	for id := 0; id < table.size(); id++ {
+		if table.token(id) == nil {
+			continue
+		}
+		eval.VocabSize++
+		if used[TokenID(id)] {
+			eval.UsedTokens++
+		} else {
+			eval.DeadTokens = append(eval.DeadTokens, id)
+		}
+	}
+	if eval.VocabSize > 0 {
+		eval.VocabUsed = 100 * float64(eval.UsedTokens) / float64(eval.VocabSize)
+	}
+
+	return eval
+}
+
+func (s *ScriptStats) ratios() {
+	if s.Tokens > 0 {
+		s.BytesPerToken = float64(s.Bytes) / float64(s.Tokens)
+		s.CharsPerToken = float64(s.Chars) / float64(s.Tokens)
+		s.WordsPerToken = float64(s.Words) / float64(s.Tokens)
+		s.OptimalSaving = 100 * float64(s.Tokens-s.OptimalTokens) / float64(s.Tokens)
+	}
+	if s.Words > 0 {
+		s.Fertility = float64(s.Tokens) / float64(s.Words)
+	}
+}
+
+// code-like symbols, a line with many of them is counted as code
+const codeSymbols = "{}[]();=<>/*&|_$#\\"
+
+/**
+ * Classify a line by script
+ * 1. Lines where at least 20% of the non-space characters are code symbols
+ *    (or that end in '{', '}' or ';') are code
+ * 2. Otherwise the script with most letters wins: latin, cyrillic or cjk
+ * 3. Lines without letters of those scripts are other
+**/
+func classifyLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return "other"
+	}
+
+	counts := make(map[string]int)
+	symbols, nonSpace := 0, 0
+	for _, r := range trimmed {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		nonSpace++
+		switch {
+		case strings.ContainsRune(codeSymbols, r):
+			symbols++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			counts["cjk"]++
+		}
+	}
+
+	last := trimmed[len(trimmed)-1]
+	if symbols*5 >= nonSpace || last == '{' || last == '}' || last == ';' {
+		return "code"
+	}
+
+	best, bestCount := "other", 0
+	for _, script := range SCRIPTS {
+		if counts[script] > bestCount {
+			best, bestCount = script, counts[script]
+		}
+	}
+	return best
+}
@@ -0,0 +1,78 @@
+package bpe
+
+import "testing"
+
+func TestClassifyLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"The quick brown fox jumps over the lazy dog.\n", "latin"},
+		{"Façade, naïve (and) café", "latin"},
+		{"Привет, как дела?", "cyrillic"},
+		{"日本語のテキストです", "cjk"},
+		{"한국어 문장", "cjk"},
+		{"func main() {", "code"},
+		{"x := map[string]int{}", "code"},
+		{"return a;", "code"},
+		{"12345 67890", "other"},
+		{"   \n", "other"},
+	}
+
+	for _, tt := range tests {
+		if got := classifyLine(tt.line); got != tt.expected {
+			t.Errorf("classifyLine(%q) = %q, want %q", tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tokenizer := newTokenizerWithMerges(Pair{104, 101}, Pair{256, 108}, Pair{257, 108})
+	corpus := "hell hello\nпривет\nif (x) { y(); }\n"
+
+	eval := tokenizer.Evaluate(corpus)
+
+	if want := len(tokenizer.Encode(corpus)); eval.Tokens != want {
+		t.Errorf("Tokens = %d, want %d", eval.Tokens, want)
+	}
+	if eval.Bytes != len(corpus) || eval.Lines != 3 || eval.Words != 8 {
+		t.Errorf("Bytes, Lines, Words = %d, %d, %d, want %d, 3, 8", eval.Bytes, eval.Lines, eval.Words, len(corpus))
+	}
+	if want := float64(eval.Bytes) / float64(eval.Tokens); eval.BytesPerToken != want {
+		t.Errorf("BytesPerToken = %v, want %v", eval.BytesPerToken, want)
+	}
+
+	for _, script := range []string{"latin", "cyrillic", "code"} {
+		if eval.Scripts[script] == nil || eval.Scripts[script].Lines != 1 {
+			t.Errorf("Scripts[%q] = %+v, want one line", script, eval.Scripts[script])
+		}
+	}
+	if len(eval.Scripts) != 3 {
+		t.Errorf("Scripts = %v, want latin, cyrillic and code only", eval.Scripts)
+	}
+
+	// "hell" is produced, "he" only ever appears inside it
+	if eval.VocabSize != 259 {
+		t.Errorf("VocabSize = %d, want 259", eval.VocabSize)
+	}
+	dead := make(map[int]bool)
+	for _, id := range eval.DeadTokens {
+		dead[id] = true
+	}
+	if !dead[256] || dead[258] || dead['\n'] || !dead[0] {
+		t.Errorf("DeadTokens = %v", eval.DeadTokens)
+	}
+	if eval.UsedTokens+len(eval.DeadTokens) != eval.VocabSize {
+		t.Errorf("UsedTokens %d + dead %d != VocabSize %d", eval.UsedTokens, len(eval.DeadTokens), eval.VocabSize)
+	}
+}
+
+func TestEvaluateEmpty(t *testing.T) {
+	eval := NewBPETokenizer().Evaluate("")
+	if eval.Tokens != 0 || eval.BytesPerToken != 0 || len(eval.Scripts) != 0 {
+		t.Errorf("Evaluate(\"\") = %+v", eval)
+	}
+	if eval.VocabSize != 256 || len(eval.DeadTokens) != 256 {
+		t.Errorf("VocabSize = %d, dead = %d, want 256, 256", eval.VocabSize, len(eval.DeadTokens))
+	}
+}
@@ -0,0 +1,132 @@
+package bpe
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// TokenStatus classifies a vocabulary entry by how it shows up in encodings
+type TokenStatus string
+
+const (
+	TokenUsed         TokenStatus = "used"         // appears in the encoding of the corpus
+	TokenIntermediate TokenStatus = "intermediate" // only appears as a part of merges into used tokens
+	TokenUnused       TokenStatus = "unused"       // reachable, but neither used nor intermediate on the corpus
+	TokenUnreachable  TokenStatus = "unreachable"  // no text encodes to it, e.g. a shadowed duplicate merge
+)
+
+// TokenFrequency is one row of the histogram
+type TokenFrequency struct {
+	ID     int         `json:"id"`
+	Count  int         `json:"count"`
+	Status TokenStatus `json:"status"`
+	Token  []byte      `json:"token"`
+}
+
+// FrequencyStats is the histogram of token ids over a corpus, ordered by id
+type FrequencyStats struct {
+	Total  int              `json:"total"`
+	Tokens []TokenFrequency `json:"tokens"`
+}
+
+/**
+ * Count how often every vocabulary id appears in the encoding of corpus
+ * 1. Encode the corpus and count the ids
+ * 2. Walk the merges from last to first, marking the parts of used or
+ *    intermediate tokens as intermediate
+ * 3. A token is unreachable when encoding its own bytes gives something else,
+ *    e.g. a later duplicate of a merge or a token spanning pre-tokenizer chunks
+**/
+func (bpe *BPETokenizer) TokenFrequencies(corpus string) *FrequencyStats {
+	table := bpe.table()
+	counts := make([]int, table.size())
+	ids := bpe.EncodeIDs(corpus)
+	for _, id := range ids {
+		if int(id) < len(counts) {
+			counts[id]++
+		}
+	}
+
+	intermediate := make([]bool, table.size())
+	for i := len(bpe.Merges) - 1; i >= 0; i-- {
+		m := bpe.Merges[i]
+		if m.Index < 0 || m.Index >= table.size() || (counts[m.Index] == 0 && !intermediate[m.Index]) {
+			continue
+		}
+		for _, part := range []int{m.Pair.First, m.Pair.Second} {
+			if part >= 0 && part < table.size() && counts[part] == 0 {
+				intermediate[part] = true
+			}
+		}
+	}
+
+	ranks := bpe.mergeRanks()
+	stats := &FrequencyStats{Total: len(ids)}
+	for id := 0; id < table.size(); id++ {
+		token := table.token(id)
+		if token == nil {
+			continue
+		}
+		freq := TokenFrequency{ID: id, Count: counts[id], Token: slices.Clone(token)}
+		switch {
+		case counts[id] > 0:
+			freq.Status = TokenUsed
+		case !bpe.reachable(id, token, ranks):
+			freq.Status = TokenUnreachable
+		case intermediate[id]:
+			freq.Status = TokenIntermediate
+		default:
+			freq.Status = TokenUnused
+		}
+		stats.Tokens = append(stats.Tokens, freq)
+	}
+
+	return stats
+}
+
+// reachable reports whether encoding the bytes of token gives back id. Tokens
+// holding part of a UTF-8 sequence only occur inside a chunk with the rest of
+// the character, so they are encoded as one chunk instead of pre-tokenized.
+func (bpe *BPETokenizer) reachable(id int, token []byte, ranks map[Pair]int) bool {
+	var ids []TokenID
+	if utf8.Valid(token) {
+		for _, c := range bpe.chunks(string(token)) {
+			ids = bpe.encodeChunk(ids, string(token[c.start:c.end]), ranks)
+		}
+	} else {
+		ids = bpe.encodeChunk(nil, string(token), ranks)
+	}
+	return len(ids) == 1 && int(ids[0]) == id
+}
+
+// WithStatus returns the ids of all tokens with the given status
+func (s *FrequencyStats) WithStatus(status TokenStatus) []int {
+	var ids []int
+	for _, t := range s.Tokens {
+		if t.Status == status {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+// WriteCSV writes the histogram as id,count,status,token with the token bytes quoted
+func (s *FrequencyStats) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "count", "status", "token"})
+	for _, t := range s.Tokens {
+		quoted := strconv.Quote(string(t.Token))
+		out.Write([]string{strconv.Itoa(t.ID), strconv.Itoa(t.Count), string(t.Status), quoted[1 : len(quoted)-1]})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes the histogram as JSON, token bytes are base64 encoded
+func (s *FrequencyStats) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
@@ -0,0 +1,88 @@
+package bpe
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTokenFrequencies(t *testing.T) {
+	tokenizer := newTokenizerWithMerges(
+		Pair{'h', 'e'}, // 256 "he"
+		Pair{256, 'l'}, // 257 "hel"
+		Pair{257, 'l'}, // 258 "hell"
+		Pair{'h', 'e'}, // 259 duplicate of 256, never produced
+	)
+
+	stats := tokenizer.TokenFrequencies("hell hello")
+
+	if stats.Total != 4 {
+		t.Errorf("Total = %d, want 4", stats.Total)
+	}
+	if len(stats.Tokens) != 260 {
+		t.Fatalf("len(Tokens) = %d, want 260", len(stats.Tokens))
+	}
+
+	tests := []struct {
+		id     int
+		count  int
+		status TokenStatus
+	}{
+		{258, 2, TokenUsed},
+		{' ', 1, TokenUsed},
+		{'o', 1, TokenUsed},
+		{257, 0, TokenIntermediate},
+		{256, 0, TokenIntermediate},
+		{'h', 0, TokenIntermediate},
+		{'l', 0, TokenIntermediate},
+		{'z', 0, TokenUnused},
+		{259, 0, TokenUnreachable},
+	}
+
+	for _, tt := range tests {
+		got := stats.Tokens[tt.id]
+		if got.ID != tt.id || got.Count != tt.count || got.Status != tt.status {
+			t.Errorf("Tokens[%d] = %+v, want count %d, status %s", tt.id, got, tt.count, tt.status)
+		}
+	}
+
+	if got := stats.WithStatus(TokenUnreachable); len(got) != 1 || got[0] != 259 {
+		t.Errorf("WithStatus(unreachable) = %v, want [259]", got)
+	}
+}
+
+func TestFrequencyStatsExport(t *testing.T) {
+	tokenizer := newTokenizerWithMerges(Pair{'h', 'i'})
+	stats := tokenizer.TokenFrequencies("hi\n")
+
+	var csv bytes.Buffer
+	if err := stats.WriteCSV(&csv); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(csv.String(), "\n"), "\n")
+	if len(lines) != 258 || lines[0] != "id,count,status,token" {
+		t.Fatalf("CSV has %d lines starting with %q", len(lines), lines[0])
+	}
+	for _, want := range []string{`10,1,used,\n`, "256,1,used,hi", "104,0,intermediate,h"} {
+		found := false
+		for _, line := range lines {
+			found = found || line == want
+		}
+		if !found {
+			t.Errorf("CSV is missing %q", want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := stats.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded FrequencyStats
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if decoded.Total != 2 || string(decoded.Tokens[256].Token) != "hi" || decoded.Tokens[256].Status != TokenUsed {
+		t.Errorf("decoded JSON = total %d, token %+v", decoded.Total, decoded.Tokens[256])
+	}
+}
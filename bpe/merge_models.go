@@ -0,0 +1,125 @@
+package bpe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MergeResult holds a tokenizer combined from two trained models together
+// with the id remapping of each input into the combined vocabulary.
+type MergeResult struct {
+	Tokenizer *BPETokenizer
+	RemapA    map[int]int // id in a -> id in Tokenizer, missing if dropped
+	RemapB    map[int]int // id in b -> id in Tokenizer, missing if dropped
+}
+
+type mergeCandidate struct {
+	merge Merge
+	token string
+	remap map[int]int
+}
+
+/**
+ * Merge two trained tokenizers into one
+ * 1. Interleave the merges of a and b by rank (a0, b0, a1, b1, ...)
+ * 2. Defer a merge until both of its parts exist in the combined vocabulary
+ * 3. Reuse the existing id when the merged token string is already known
+ * 4. Otherwise append the merge with the next free index starting at 256
+ * 5. Stop once the combined vocabulary reaches targetSize (0 means no limit)
+**/
+func MergeModels(a, b *BPETokenizer, targetSize int) (*MergeResult, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("merge models: nil tokenizer")
+	}
+	if len(a.Chars) > 0 || len(b.Chars) > 0 {
+		return nil, errors.New("merge models: character alphabets are not supported")
+	}
+	if (a.SentencePiece == nil) != (b.SentencePiece == nil) ||
+		(a.SentencePiece != nil && *a.SentencePiece != *b.SentencePiece) {
+		return nil, errors.New("merge models: models use different pre-tokenization")
+	}
+	if a.CrossChunks != b.CrossChunks {
+		return nil, errors.New("merge models: only one model merges across chunks")
+	}
+	if targetSize < 0 || (targetSize > 0 && targetSize < 256) {
+		return nil, fmt.Errorf("merge models: target size %d is smaller than the base vocabulary of 256", targetSize)
+	}
+
+	result := &MergeResult{
+		Tokenizer: NewBPETokenizer(),
+		RemapA:    make(map[int]int),
+		RemapB:    make(map[int]int),
+	}
+	if a.SentencePiece != nil {
+		spm := *a.SentencePiece
+		result.Tokenizer.SentencePiece = &spm
+	}
+	result.Tokenizer.CrossChunks = a.CrossChunks
+	for i := 0; i < 256; i++ {
+		result.RemapA[i] = i
+		result.RemapB[i] = i
+	}
+
+	tableA := a.table()
+	tableB := b.table()
+	known := make(map[string]int) // token -> id in the combined vocabulary
+	for i := 0; i < 256; i++ {
+		known[string([]byte{byte(i)})] = i
+	}
+
+	var queue []mergeCandidate
+	for i := 0; i < len(a.Merges) || i < len(b.Merges); i++ {
+		if i < len(a.Merges) {
+			m := a.Merges[i]
+			queue = append(queue, mergeCandidate{m, string(tableA.token(m.Index)), result.RemapA})
+		}
+		if i < len(b.Merges) {
+			m := b.Merges[i]
+			queue = append(queue, mergeCandidate{m, string(tableB.token(m.Index)), result.RemapB})
+		}
+	}
+
+	merged := result.Tokenizer
+	full := func() bool {
+		return targetSize > 0 && 256+len(merged.Merges) >= targetSize
+	}
+
+	for len(queue) > 0 {
+		progressed := false
+		var pending []mergeCandidate
+
+		for _, c := range queue {
+			first, okFirst := c.remap[c.merge.Pair.First]
+			second, okSecond := c.remap[c.merge.Pair.Second]
+			if !okFirst || !okSecond {
+				pending = append(pending, c)
+				continue
+			}
+
+			if id, exists := known[c.token]; exists {
+				c.remap[c.merge.Index] = id
+				progressed = true
+				continue
+			}
+
+			if full() {
+				continue
+			}
+
+			idx := 256 + len(merged.Merges)
+			known[c.token] = idx
+			merged.Merges = append(merged.Merges, Merge{Pair{first, second}, idx})
+			merged.vocabSize++
+			c.remap[c.merge.Index] = idx
+			progressed = true
+		}
+
+		if !progressed {
+			break
+		}
+		queue = pending
+	}
+	merged.buildVocab()
+
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package bpe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTokenizerWithMerges(pairs ...Pair) *BPETokenizer {
+	tokenizer := NewBPETokenizer()
+	for i, pair := range pairs {
+		tokenizer.Merges = append(tokenizer.Merges, Merge{Pair: pair, Index: 256 + i})
+	}
+	return tokenizer
+}
+
+func TestMergeModels(t *testing.T) {
+	// a: "he" (256), "hel" (257)
+	a := newTokenizerWithMerges(Pair{'h', 'e'}, Pair{256, 'l'})
+	// b: "wo" (256), "he" (257), "wor" (258)
+	b := newTokenizerWithMerges(Pair{'w', 'o'}, Pair{'h', 'e'}, Pair{256, 'r'})
+
+	result, err := MergeModels(a, b, 0)
+	if err != nil {
+		t.Fatalf("MergeModels() error = %v", err)
+	}
+
+	expectedMerges := []Merge{
+		{Pair{'h', 'e'}, 256},
+		{Pair{'w', 'o'}, 257},
+		{Pair{256, 'l'}, 258},
+		{Pair{257, 'r'}, 259},
+	}
+	if !reflect.DeepEqual(result.Tokenizer.Merges, expectedMerges) {
+		t.Errorf("Merges = %v, want %v", result.Tokenizer.Merges, expectedMerges)
+	}
+
+	if result.RemapA[256] != 256 || result.RemapA[257] != 258 {
+		t.Errorf("RemapA = %v, want 256->256, 257->258", result.RemapA)
+	}
+	if result.RemapB[256] != 257 || result.RemapB[257] != 256 || result.RemapB[258] != 259 {
+		t.Errorf("RemapB = %v, want 256->257, 257->256, 258->259", result.RemapB)
+	}
+	if result.RemapA['x'] != 'x' || result.RemapB['x'] != 'x' {
+		t.Error("base byte ids should map to themselves")
+	}
+}
+
+func TestMergeModelsDuplicateString(t *testing.T) {
+	// a builds "abc" as "ab"+"c", b builds it as "a"+"bc"
+	a := newTokenizerWithMerges(Pair{'a', 'b'}, Pair{256, 'c'})
+	b := newTokenizerWithMerges(Pair{'b', 'c'}, Pair{'a', 256})
+
+	result, err := MergeModels(a, b, 0)
+	if err != nil {
+		t.Fatalf("MergeModels() error = %v", err)
+	}
+
+	if len(result.Tokenizer.Merges) != 3 {
+		t.Fatalf("expected 3 merges (ab, bc, abc), got %v", result.Tokenizer.Merges)
+	}
+	if result.RemapA[257] != result.RemapB[257] {
+		t.Errorf("abc should map to the same id, got a=%d b=%d", result.RemapA[257], result.RemapB[257])
+	}
+
+	text := "abcabc bc"
+	decoded := result.Tokenizer.Decode(result.Tokenizer.Encode(text))
+	if decoded != text {
+		t.Errorf("round trip = %q, want %q", decoded, text)
+	}
+}
+
+func TestMergeModelsTargetSize(t *testing.T) {
+	a := newTokenizerWithMerges(Pair{'h', 'e'}, Pair{256, 'l'})
+	b := newTokenizerWithMerges(Pair{'w', 'o'}, Pair{256, 'r'})
+
+	result, err := MergeModels(a, b, 258)
+	if err != nil {
+		t.Fatalf("MergeModels() error = %v", err)
+	}
+
+	if len(result.Tokenizer.Merges) != 2 {
+		t.Errorf("expected 2 merges, got %d", len(result.Tokenizer.Merges))
+	}
+	if _, ok := result.RemapA[257]; ok {
+		t.Error("dropped token of a should not be remapped")
+	}
+	if _, ok := result.RemapB[257]; ok {
+		t.Error("dropped token of b should not be remapped")
+	}
+}
+
+func TestMergeModelsInvalidTargetSize(t *testing.T) {
+	a := NewBPETokenizer()
+	b := NewBPETokenizer()
+
+	if _, err := MergeModels(a, b, 100); err == nil {
+		t.Error("expected error for target size below 256")
+	}
+	if _, err := MergeModels(a, nil, 0); err == nil {
+		t.Error("expected error for nil tokenizer")
+	}
+}
@@ -0,0 +1,75 @@
+package bpe
+
+import "unicode/utf8"
+
+// Token is an encoded token together with its position in the input text.
+// Rune offsets are widened to whole characters, so a token holding only part
+// of a multi-byte character still covers that character.
+type Token struct {
+	ID        int
+	Start     int // byte offset of the first byte
+	End       int // byte offset one past the last byte
+	RuneStart int // rune offset of the first character
+	RuneEnd   int // rune offset one past the last character
+	Chunk     int // index of the pre-tokenizer chunk the token came from
+}
+
+/**
+ * Encode text into tokens with offsets
+ * 1. Split text into pre-tokenizer chunks
+ * 2. Encode each chunk exactly like Encode
+ * 3. Walk the chunk using the byte length of each token to get its span
+ * 4. Map byte spans to rune spans, the space of a SentencePiece dummy prefix
+ *    is not in text so it adds nothing to the span of its token
+**/
+func (bpe *BPETokenizer) EncodeWithOffsets(text string) []Token {
+	ranks := bpe.mergeRanks()
+	table := bpe.table()
+	runeAt := runeOffsets(text)
+
+	normalized := bpe.normalize(text)
+	prefix := len(normalized) - len(text)
+
+	var ids []TokenID
+	tokens := []Token{}
+	for i, c := range bpe.chunks(normalized) {
+		start := c.start
+		ids = bpe.encodeChunk(ids[:0], normalized[c.start:c.end], ranks)
+		for _, id := range ids {
+			end := start + len(table.token(int(id)))
+			token := Token{
+				ID:    int(id),
+				Start: max(start-prefix, 0),
+				End:   max(end-prefix, 0),
+				Chunk: i,
+			}
+			token.RuneStart = runeAt[token.Start]
+			token.RuneEnd = token.RuneStart
+			if token.End > token.Start {
+				token.RuneEnd = runeAt[token.End-1] + 1
+			}
+			tokens = append(tokens, token)
+			start = end
+		}
+	}
+
+	return tokens
+}
+
+// runeOffsets returns, for each byte offset, the index of the rune containing
+// that byte. The extra last entry holds the total rune count. Invalid bytes
+// count as one rune each, like a []rune conversion.
+func runeOffsets(text string) []int {
+	runeAt := make([]int, len(text)+1)
+	runeIndex := 0
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		for j := i; j < i+size; j++ {
+			runeAt[j] = runeIndex
+		}
+		i += size
+		runeIndex++
+	}
+	runeAt[len(text)] = runeIndex
+	return runeAt
+}
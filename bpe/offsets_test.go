@@ -0,0 +1,106 @@
+package bpe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeWithOffsets(t *testing.T) {
+	tests := []struct {
+		name     string
+		merges   []Pair
+		text     string
+		expected []Token
+	}{
+		{
+			name: "ascii chunks",
+			text: "hi yo",
+			expected: []Token{
+				{ID: 'h', Start: 0, End: 1, RuneStart: 0, RuneEnd: 1, Chunk: 0},
+				{ID: 'i', Start: 1, End: 2, RuneStart: 1, RuneEnd: 2, Chunk: 0},
+				{ID: ' ', Start: 2, End: 3, RuneStart: 2, RuneEnd: 3, Chunk: 1},
+				{ID: 'y', Start: 3, End: 4, RuneStart: 3, RuneEnd: 4, Chunk: 1},
+				{ID: 'o', Start: 4, End: 5, RuneStart: 4, RuneEnd: 5, Chunk: 1},
+			},
+		},
+		{
+			name:   "merged token",
+			merges: []Pair{{'h', 'i'}},
+			text:   "hi\nhi",
+			expected: []Token{
+				{ID: 256, Start: 0, End: 2, RuneStart: 0, RuneEnd: 2, Chunk: 0},
+				{ID: '\n', Start: 2, End: 3, RuneStart: 2, RuneEnd: 3, Chunk: 1},
+				{ID: 256, Start: 3, End: 5, RuneStart: 3, RuneEnd: 5, Chunk: 2},
+			},
+		},
+		{
+			name:   "multi-byte character split across tokens",
+			merges: []Pair{{'a', 0xC3}}, // "é" is 0xC3 0xA9
+			text:   "aé",
+			expected: []Token{
+				{ID: 256, Start: 0, End: 2, RuneStart: 0, RuneEnd: 2, Chunk: 0},
+				{ID: 0xA9, Start: 2, End: 3, RuneStart: 1, RuneEnd: 2, Chunk: 0},
+			},
+		},
+		{
+			name:   "token spanning two partial characters",
+			merges: []Pair{{0xA5, 0xE6}}, // "日" is E6 97 A5, "本" is E6 9C AC
+			text:   "日本",
+			expected: []Token{
+				{ID: 0xE6, Start: 0, End: 1, RuneStart: 0, RuneEnd: 1, Chunk: 0},
+				{ID: 0x97, Start: 1, End: 2, RuneStart: 0, RuneEnd: 1, Chunk: 0},
+				{ID: 256, Start: 2, End: 4, RuneStart: 0, RuneEnd: 2, Chunk: 0},
+				{ID: 0x9C, Start: 4, End: 5, RuneStart: 1, RuneEnd: 2, Chunk: 0},
+				{ID: 0xAC, Start: 5, End: 6, RuneStart: 1, RuneEnd: 2, Chunk: 0},
+			},
+		},
+		{
+			name:     "empty text",
+			text:     "",
+			expected: []Token{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := newTokenizerWithMerges(tt.merges...)
+			result := tokenizer.EncodeWithOffsets(tt.text)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("EncodeWithOffsets() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncodeWithOffsetsMatchesEncode(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	text := "héllo wörld, héllo 123!\n日本語 wörld"
+	tokenizer.Train(text)
+
+	tokens := tokenizer.EncodeWithOffsets(text)
+	ids := tokenizer.Encode(text)
+
+	if len(tokens) != len(ids) {
+		t.Fatalf("got %d tokens, Encode returned %d ids", len(tokens), len(ids))
+	}
+
+	var rebuilt string
+	lastChunk := 0
+	for i, tok := range tokens {
+		if tok.ID != ids[i] {
+			t.Errorf("token %d has id %d, Encode returned %d", i, tok.ID, ids[i])
+		}
+		if tok.Chunk < lastChunk {
+			t.Errorf("token %d has chunk %d after chunk %d", i, tok.Chunk, lastChunk)
+		}
+		lastChunk = tok.Chunk
+		if tokenizer.Decode([]int{tok.ID}) != text[tok.Start:tok.End] {
+			t.Errorf("token %d decodes to %q, span holds %q", i, tokenizer.Decode([]int{tok.ID}), text[tok.Start:tok.End])
+		}
+		rebuilt += text[tok.Start:tok.End]
+	}
+
+	if rebuilt != text {
+		t.Errorf("spans rebuild %q, want %q", rebuilt, text)
+	}
+}
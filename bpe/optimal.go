@@ -0,0 +1,62 @@
+package bpe
+
+/**
+ * Encode text into the fewest tokens of the vocabulary
+ * 1. Split text into chunks like Encode, tokens still never cross chunks
+ * 2. Find the shortest segmentation of each chunk by dynamic programming over
+ *    the token trie, instead of applying merges in rank order
+ * The result decodes to text like Encode but is never longer, and usually the
+ * same length: greedy merges only lose where an early merge blocks a longer
+ * token. It is the first of Segmentations(chunk, 1, ByTokenCount) per chunk.
+**/
+func (bpe *BPETokenizer) EncodeOptimal(text string) []int {
+	return toInts(bpe.appendEncodeOptimal(nil, text, bpe.table()))
+}
+
+// EncodeOptimal encodes text into the fewest tokens, see BPETokenizer.EncodeOptimal
+func (e *Encoder) EncodeOptimal(text string) []int {
+	return toInts(e.tokenizer.appendEncodeOptimal(nil, text, e.table))
+}
+
+func (bpe *BPETokenizer) appendEncodeOptimal(dst []TokenID, text string, table *vocabulary) []TokenID {
+	text = bpe.normalize(text)
+	for _, c := range bpe.chunks(text) {
+		dst = appendOptimalChunk(dst, text[c.start:c.end], table.trie())
+	}
+	return dst
+}
+
+/**
+ * Append the shortest segmentation of chunk to dst
+ * 1. For every end position keep the fewest tokens covering the chunk up to
+ *    it, with the start and id of its last token. Ties go to the longer last
+ *    token, like Segmentations
+ * 2. Follow the last tokens back from the end and append them in order
+**/
+func appendOptimalChunk(dst []TokenID, chunk string, trie *tokenTrie) []TokenID {
+	if chunk == "" {
+		return dst
+	}
+
+	count := make([]int, len(chunk)+1) // tokens up to each end, 0 where unreachable
+	from := make([]int, len(chunk)+1)
+	last := make([]TokenID, len(chunk)+1)
+	for start := 0; start < len(chunk); start++ {
+		if start > 0 && count[start] == 0 {
+			continue
+		}
+		trie.walk(chunk[start:], func(id TokenID, length int) {
+			end := start + length
+			if count[end] == 0 || count[start]+1 < count[end] {
+				count[end], from[end], last[end] = count[start]+1, start, id
+			}
+		})
+	}
+
+	n := count[len(chunk)]
+	dst = append(dst, make([]TokenID, n)...)
+	for i, end := len(dst)-1, len(chunk); end > 0; i, end = i-1, from[end] {
+		dst[i] = last[end]
+	}
+	return dst
+}
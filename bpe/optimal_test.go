@@ -0,0 +1,79 @@
+package bpe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeOptimal(t *testing.T) {
+	// "ab" (256) is merged first, so Encode never reaches "abc" (258) = "a" + "bc" (257)
+	tokenizer := newTokenizerWithMerges(Pair{'a', 'b'}, Pair{'b', 'c'}, Pair{'a', 257})
+
+	tests := []struct {
+		name     string
+		text     string
+		greedy   []int
+		expected []int
+	}{
+		{"greedy loses", "abc", []int{256, 'c'}, []int{258}},
+		{"same as greedy", "ab", []int{256}, []int{256}},
+		{"per chunk", "abc abc", []int{256, 'c', ' ', 256, 'c'}, []int{258, ' ', 258}},
+		{"empty", "", []int{}, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizer.Encode(tt.text); !reflect.DeepEqual(got, tt.greedy) {
+				t.Errorf("Encode() = %v, want %v", got, tt.greedy)
+			}
+			got := tokenizer.EncodeOptimal(tt.text)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("EncodeOptimal() = %v, want %v", got, tt.expected)
+			}
+			if decoded := tokenizer.Decode(got); decoded != tt.text {
+				t.Errorf("Decode(EncodeOptimal()) = %q, want %q", decoded, tt.text)
+			}
+		})
+	}
+
+	eval := tokenizer.Evaluate("abc abc\n")
+	if eval.Tokens != 6 || eval.OptimalTokens != 4 {
+		t.Errorf("Evaluate() Tokens, OptimalTokens = %d, %d, want 6, 4", eval.Tokens, eval.OptimalTokens)
+	}
+	if want := 100 * 2.0 / 6; eval.OptimalSaving != want {
+		t.Errorf("OptimalSaving = %v, want %v", eval.OptimalSaving, want)
+	}
+}
+
+func TestEncodeOptimalTrained(t *testing.T) {
+	corpus := strings.Repeat("the quick brown fox jumps over the lazy dog\nhello héllo 日本語\n", 10)
+	tokenizer := NewBPETokenizer()
+	tokenizer.TrainWithOptions(corpus, TrainOptions{VocabSize: 320})
+	encoder := tokenizer.Freeze()
+	text := "the lazy fox says hello 日本語, then jumps"
+
+	optimal := tokenizer.EncodeOptimal(text)
+	if got := tokenizer.Decode(optimal); got != text {
+		t.Errorf("Decode(EncodeOptimal()) = %q, want %q", got, text)
+	}
+	if len(optimal) > len(tokenizer.Encode(text)) {
+		t.Errorf("EncodeOptimal() has %d tokens, more than Encode() %d", len(optimal), len(tokenizer.Encode(text)))
+	}
+	if got := encoder.EncodeOptimal(text); !reflect.DeepEqual(got, optimal) {
+		t.Errorf("Freeze().EncodeOptimal() = %v, want %v", got, optimal)
+	}
+
+	// each chunk gets the best segmentation by token count
+	var best []int
+	for _, c := range splitChunks(text) {
+		segmentations, err := tokenizer.Segmentations(text[c.start:c.end], 1, ByTokenCount)
+		if err != nil {
+			t.Fatalf("Segmentations() error = %v", err)
+		}
+		best = append(best, segmentations[0].IDs...)
+	}
+	if !reflect.DeepEqual(optimal, best) {
+		t.Errorf("EncodeOptimal() = %v, want the best segmentations %v", optimal, best)
+	}
+}
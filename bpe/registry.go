@@ -0,0 +1,86 @@
+package bpe
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// registeredModel is a model file in a file system, loaded on first use
+type registeredModel struct {
+	fsys    fs.FS
+	path    string
+	once    sync.Once
+	encoder *Encoder
+	err     error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*registeredModel)
+)
+
+/**
+ * Register a model under name, typically from an init function with an
+ * embed.FS so the model is compiled into the binary
+ * 1. The model at path in fsys may be in the text or the binary format
+ * 2. It is only read on the first Get
+ * 3. Like sql.Register it panics if fsys is nil or name is already taken
+**/
+func Register(name string, fsys fs.FS, path string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if fsys == nil {
+		panic("bpe: Register file system is nil")
+	}
+	if _, exists := registry[name]; exists {
+		panic("bpe: Register called twice for model " + name)
+	}
+	registry[name] = &registeredModel{fsys: fsys, path: path}
+}
+
+/**
+ * Get the Encoder of a registered model
+ * 1. Load and freeze the model the first time it is asked for
+ * 2. Later calls return the same Encoder, or the same load error
+**/
+func Get(name string) (*Encoder, error) {
+	registryMu.RLock()
+	model, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("bpe: unknown model %q", name)
+	}
+
+	model.once.Do(func() {
+		data, err := fs.ReadFile(model.fsys, model.path)
+		if err != nil {
+			model.err = fmt.Errorf("bpe: model %q: %w", name, err)
+			return
+		}
+		tokenizer := NewBPETokenizer()
+		if err := tokenizer.LoadReader(bytes.NewReader(data)); err != nil {
+			model.err = fmt.Errorf("bpe: model %q: %s: %w", name, model.path, err)
+			return
+		}
+		model.encoder = tokenizer.Freeze()
+	})
+
+	return model.encoder, model.err
+}
+
+// Models returns the names of all registered models, sorted
+func Models() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
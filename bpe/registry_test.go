@@ -0,0 +1,110 @@
+package bpe
+
+import (
+	"bytes"
+	"embed"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata/hello.model
+var testModels embed.FS
+
+// models are registered once per test binary, so tests can run with -count
+func init() {
+	var binary bytes.Buffer
+	newTokenizerWithMerges(Pair{'h', 'i'}).WriteBinary(&binary)
+
+	Register("test-embed", testModels, "testdata/hello.model")
+	Register("test-binary", fstest.MapFS{"m.bin": {Data: binary.Bytes()}}, "m.bin")
+	Register("test-missing", fstest.MapFS{}, "missing.model")
+	Register("test-invalid", fstest.MapFS{"bad.model": {Data: []byte("not a model")}}, "bad.model")
+	Register("test-concurrent", testModels, "testdata/hello.model")
+}
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []int
+		wantErr  bool
+	}{
+		{name: "test-embed", text: "hello", expected: []int{259}},
+		{name: "test-binary", text: "hi", expected: []int{256}},
+		{name: "test-missing", wantErr: true},
+		{name: "test-invalid", wantErr: true},
+		{name: "test-unregistered", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := Get(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got := encoder.Encode(tt.text); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.expected)
+			}
+		})
+	}
+
+	names := Models()
+	for _, name := range []string{"test-binary", "test-embed", "test-invalid", "test-missing"} {
+		found := false
+		for _, n := range names {
+			found = found || n == name
+		}
+		if !found {
+			t.Errorf("Models() = %v, missing %s", names, name)
+		}
+	}
+}
+
+// Run with -race, concurrent first Gets load the model once
+func TestRegistryConcurrentGet(t *testing.T) {
+	encoders := make([]*Encoder, 8)
+	var wg sync.WaitGroup
+	for i := range encoders {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			encoders[i], _ = Get("test-concurrent")
+		}(i)
+	}
+	wg.Wait()
+
+	for _, encoder := range encoders {
+		if encoder == nil || encoder != encoders[0] {
+			t.Fatalf("Get returned different encoders: %v", encoders)
+		}
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func()
+	}{
+		{"duplicate name", func() { Register("test-embed", testModels, "testdata/hello.model") }},
+		{"nil file system", func() { Register("test-nil", nil, "vocab.model") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			tt.register()
+		})
+	}
+}
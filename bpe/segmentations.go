@@ -0,0 +1,120 @@
+package bpe
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SegmentationOrder decides which segmentations Segmentations returns first
+type SegmentationOrder int
+
+const (
+	// ByTokenCount puts segmentations with the fewest tokens first
+	ByTokenCount SegmentationOrder = iota
+	// ByMergeRank adds up the rank of every token, one past its position in
+	// Merges and len(Merges)+1 for base tokens, so segmentations of few and
+	// early merged tokens come first
+	ByMergeRank
+)
+
+// MAX_SEGMENTATION_STATES bounds (len(chunk)+1) * k, the number of partial
+// segmentations Segmentations keeps, as the number of segmentations of a
+// chunk grows exponentially with its length
+const MAX_SEGMENTATION_STATES = 1 << 22
+
+// Segmentation is one way to split a chunk into tokens of the vocabulary
+type Segmentation struct {
+	IDs  []int
+	Cost int // tokens for ByTokenCount, sum of ranks for ByMergeRank
+}
+
+// segmentState is one of the k best segmentations of a prefix of the chunk,
+// linked to the segmentation of the prefix before its last token
+type segmentState struct {
+	cost  int
+	start int // where the last token starts
+	prev  int // index of the prefix segmentation in states[start]
+	id    TokenID
+}
+
+/**
+ * Enumerate up to k segmentations of chunk into tokens of the vocabulary
+ * 1. Find the tokens starting at every byte with the token trie
+ * 2. Keep the k cheapest segmentations of every prefix, extending those of
+ *    shorter prefixes by one token. Ties go to the longer last token, so the
+ *    result is deterministic
+ * 3. Follow the links back from the end to build the ids
+ * The chunk is not pre-tokenized, Encode returns one of the segmentations of
+ * each of its chunks. Segmentations fails when k is not positive or the chunk
+ * is too long for k, see MAX_SEGMENTATION_STATES.
+**/
+func (bpe *BPETokenizer) Segmentations(chunk string, k int, order SegmentationOrder) ([]Segmentation, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("segmentations: k must be positive, got %d", k)
+	}
+	if (len(chunk)+1)*k > MAX_SEGMENTATION_STATES {
+		return nil, fmt.Errorf("segmentations: %d bytes with k=%d exceed %d states", len(chunk), k, MAX_SEGMENTATION_STATES)
+	}
+	if chunk == "" {
+		return []Segmentation{{IDs: []int{}}}, nil
+	}
+
+	table := bpe.table()
+	cost := func(TokenID) int { return 1 }
+	if order == ByMergeRank {
+		ranks := make(map[TokenID]int, len(bpe.Merges))
+		for i, m := range bpe.Merges {
+			if _, ok := ranks[TokenID(m.Index)]; !ok {
+				ranks[TokenID(m.Index)] = i
+			}
+		}
+		cost = func(id TokenID) int {
+			if rank, ok := ranks[id]; ok {
+				return rank + 1
+			}
+			return len(bpe.Merges) + 1
+		}
+	}
+
+	states := make([][]segmentState, len(chunk)+1)
+	states[0] = []segmentState{{}}
+	trie := table.trie()
+	for start := 0; start < len(chunk); start++ {
+		if len(states[start]) > 0 {
+			trie.walk(chunk[start:], func(id TokenID, length int) {
+				end := start + length
+				for prev, state := range states[start] {
+					states[end] = append(states[end], segmentState{state.cost + cost(id), start, prev, id})
+				}
+			})
+		}
+		// every token ending at start+1 has been seen, keep the k best
+		next := states[start+1]
+		sort.SliceStable(next, func(i, j int) bool {
+			if next[i].cost != next[j].cost {
+				return next[i].cost < next[j].cost
+			}
+			return next[i].start < next[j].start
+		})
+		states[start+1] = next[:min(len(next), k)]
+	}
+
+	segmentations := []Segmentation{}
+	for _, state := range states[len(chunk)] {
+		s := Segmentation{Cost: state.cost}
+		for end := len(chunk); end > 0; {
+			s.IDs = append(s.IDs, int(state.id))
+			end, state = state.start, states[state.start][state.prev]
+		}
+		for i, j := 0, len(s.IDs)-1; i < j; i, j = i+1, j-1 {
+			s.IDs[i], s.IDs[j] = s.IDs[j], s.IDs[i]
+		}
+		segmentations = append(segmentations, s)
+	}
+	return segmentations, nil
+}
+
+// Segmentations enumerates up to k segmentations of chunk, see BPETokenizer.Segmentations
+func (e *Encoder) Segmentations(chunk string, k int, order SegmentationOrder) ([]Segmentation, error) {
+	return e.tokenizer.Segmentations(chunk, k, order)
+}
@@ -0,0 +1,139 @@
+package bpe
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSegmentations(t *testing.T) {
+	// "he" (256), "ll" (257), "hell" (258)
+	tokenizer := newTokenizerWithMerges(Pair{'h', 'e'}, Pair{'l', 'l'}, Pair{256, 257})
+	h, e, l := int('h'), int('e'), int('l')
+
+	tests := []struct {
+		name     string
+		chunk    string
+		k        int
+		order    SegmentationOrder
+		expected []Segmentation
+	}{
+		{
+			name:  "by token count",
+			chunk: "hell",
+			k:     10,
+			order: ByTokenCount,
+			expected: []Segmentation{
+				{[]int{258}, 1},
+				{[]int{256, 257}, 2},
+				{[]int{h, e, 257}, 3}, // longer last token first on ties
+				{[]int{256, l, l}, 3},
+				{[]int{h, e, l, l}, 4},
+			},
+		},
+		{
+			name:  "by merge rank",
+			chunk: "hell",
+			k:     10,
+			order: ByMergeRank,
+			expected: []Segmentation{
+				{[]int{258}, 3},
+				{[]int{256, 257}, 3},
+				{[]int{256, l, l}, 9},
+				{[]int{h, e, 257}, 10},
+				{[]int{h, e, l, l}, 16},
+			},
+		},
+		{
+			name:     "k best",
+			chunk:    "hell",
+			k:        2,
+			order:    ByTokenCount,
+			expected: []Segmentation{{[]int{258}, 1}, {[]int{256, 257}, 2}},
+		},
+		{
+			name:     "no merges",
+			chunk:    "ab",
+			k:        5,
+			order:    ByTokenCount,
+			expected: []Segmentation{{[]int{'a', 'b'}, 2}},
+		},
+		{
+			name:     "empty",
+			chunk:    "",
+			k:        3,
+			order:    ByTokenCount,
+			expected: []Segmentation{{[]int{}, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenizer.Segmentations(tt.chunk, tt.k, tt.order)
+			if err != nil {
+				t.Fatalf("Segmentations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Segmentations() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSegmentationsTrained(t *testing.T) {
+	corpus := strings.Repeat("the quick brown fox jumps over the lazy dog\nhello héllo 日本語\n", 10)
+	tokenizer := NewBPETokenizer()
+	tokenizer.TrainWithOptions(corpus, TrainOptions{VocabSize: 320})
+	encoder := tokenizer.Freeze()
+
+	for _, chunk := range []string{" lazy", "hello", " 日本語"} {
+		for _, order := range []SegmentationOrder{ByTokenCount, ByMergeRank} {
+			segmentations, err := encoder.Segmentations(chunk, 50, order)
+			if err != nil {
+				t.Fatalf("Segmentations(%q) error = %v", chunk, err)
+			}
+			if len(segmentations) == 0 || len(segmentations) > 50 {
+				t.Fatalf("Segmentations(%q) returned %d segmentations", chunk, len(segmentations))
+			}
+
+			seen := make(map[string]bool)
+			encoded := fmt.Sprint(tokenizer.Encode(chunk))
+			for i, s := range segmentations {
+				if got := tokenizer.Decode(s.IDs); got != chunk {
+					t.Errorf("segmentation %v decodes to %q, want %q", s.IDs, got, chunk)
+				}
+				if i > 0 && s.Cost < segmentations[i-1].Cost {
+					t.Errorf("segmentation %d costs %d, less than the one before", i, s.Cost)
+				}
+				if seen[fmt.Sprint(s.IDs)] {
+					t.Errorf("segmentation %v returned twice", s.IDs)
+				}
+				seen[fmt.Sprint(s.IDs)] = true
+			}
+			if order == ByTokenCount && len(segmentations[0].IDs) > len(tokenizer.Encode(chunk)) {
+				t.Errorf("best segmentation %v is longer than Encode() %s", segmentations[0].IDs, encoded)
+			}
+		}
+	}
+}
+
+func TestSegmentationsLimits(t *testing.T) {
+	tokenizer := newTokenizerWithMerges(Pair{'a', 'a'})
+
+	if _, err := tokenizer.Segmentations("aa", 0, ByTokenCount); err == nil {
+		t.Error("expected an error for k = 0")
+	}
+	if _, err := tokenizer.Segmentations(strings.Repeat("a", 1<<12), 1<<12, ByTokenCount); err == nil {
+		t.Error("expected an error above MAX_SEGMENTATION_STATES")
+	}
+
+	// the segmentations are counted by Fibonacci numbers, only the k best are ever kept
+	segmentations, err := tokenizer.Segmentations(strings.Repeat("a", 200), 3, ByTokenCount)
+	if err != nil {
+		t.Fatalf("Segmentations() error = %v", err)
+	}
+	if len(segmentations) != 3 || segmentations[0].Cost != 100 || segmentations[2].Cost != 101 {
+		t.Errorf("Segmentations() costs = %v", segmentations)
+	}
+}
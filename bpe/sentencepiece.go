@@ -0,0 +1,211 @@
+package bpe
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SPACE_MARKER is how SentencePiece shows the space starting a word
+const SPACE_MARKER = "▁" // U+2581
+
+/**
+ * SentencePieceOptions switch the tokenizer from GPT4_SPLIT_PATTERN
+ * pre-tokenization to the SentencePiece-style raw stream, for languages
+ * without spaces between words
+ * 1. Every space is the ▁ word marker, it starts the word after it
+ * 2. Merges run over whole lines and only stop at the boundaries the rules
+ *    below ask for, line breaks are always boundaries
+ * 3. The vocabulary keeps plain spaces, so decoding gives back the original
+ *    whitespace, ▁ only shows up in Piece
+**/
+type SentencePieceOptions struct {
+	DummyPrefix  bool // prepend ▁ to the text so its first word is encoded like every other word
+	CrossWords   bool // let merges span ▁, e.g. "of▁the", instead of ▁ only starting a token
+	SplitDigits  bool // never merge digits
+	SplitScripts bool // never merge letters of different scripts, Han and kana count as one
+}
+
+// sentencePieceFlags lists the text model flag of each option
+var sentencePieceFlags = []struct {
+	name  string
+	field func(*SentencePieceOptions) *bool
+}{
+	{"dummy-prefix", func(o *SentencePieceOptions) *bool { return &o.DummyPrefix }},
+	{"cross-words", func(o *SentencePieceOptions) *bool { return &o.CrossWords }},
+	{"split-digits", func(o *SentencePieceOptions) *bool { return &o.SplitDigits }},
+	{"split-scripts", func(o *SentencePieceOptions) *bool { return &o.SplitScripts }},
+}
+
+// String returns the "sentencepiece flag..." line of the text model format
+func (o SentencePieceOptions) String() string {
+	parts := []string{"sentencepiece"}
+	for _, flag := range sentencePieceFlags {
+		if *flag.field(&o) {
+			parts = append(parts, flag.name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseSentencePieceLine parses a line written by SentencePieceOptions.String
+func parseSentencePieceLine(line string) (*SentencePieceOptions, error) {
+	rules, ok := strings.CutPrefix(line, "sentencepiece")
+	if !ok || (rules != "" && rules[0] != ' ') {
+		return nil, fmt.Errorf("invalid sentencepiece line %q", line)
+	}
+	return ParseSentencePieceOptions(rules)
+}
+
+// ParseSentencePieceOptions parses rule names separated by commas or spaces,
+// e.g. "dummy-prefix,split-digits", unnamed rules are off
+func ParseSentencePieceOptions(rules string) (*SentencePieceOptions, error) {
+	opts := &SentencePieceOptions{}
+	names := strings.FieldsFunc(rules, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, name := range names {
+		found := false
+		for _, flag := range sentencePieceFlags {
+			if flag.name == name {
+				*flag.field(opts) = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown sentencepiece option %q", name)
+		}
+	}
+	return opts, nil
+}
+
+// bits packs the options for the binary model format, bit 0 marks the mode as enabled
+func (o SentencePieceOptions) bits() uint32 {
+	bits := uint32(1)
+	for i, flag := range sentencePieceFlags {
+		if *flag.field(&o) {
+			bits |= 1 << (i + 1)
+		}
+	}
+	return bits
+}
+
+// sentencePieceFromBits unpacks bits, nil when the mode is not enabled
+func sentencePieceFromBits(bits uint32) (*SentencePieceOptions, error) {
+	if bits == 0 {
+		return nil, nil
+	}
+	if bits&1 == 0 || bits>>(len(sentencePieceFlags)+1) != 0 {
+		return nil, fmt.Errorf("invalid sentencepiece flags %#x", bits)
+	}
+	opts := &SentencePieceOptions{}
+	for i, flag := range sentencePieceFlags {
+		*flag.field(opts) = bits&(1<<(i+1)) != 0
+	}
+	return opts, nil
+}
+
+/**
+ * Split text into chunks at the boundaries of the options
+ * 1. A line break is a chunk of its own
+ * 2. Unless CrossWords is set, every space starts a chunk
+ * 3. SplitDigits makes every digit a chunk, SplitScripts starts a chunk where
+ *    the script of the letters changes
+**/
+func splitSentencePiece(text string, opts SentencePieceOptions) []chunk {
+	var chunks []chunk
+	start := 0
+	prev, prevScript := rune(-1), -1
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		script := letterScript(r)
+
+		boundary := prev == '\n' || r == '\n' ||
+			(!opts.CrossWords && r == ' ') ||
+			(opts.SplitDigits && (isDigit(prev) || isDigit(r))) ||
+			(opts.SplitScripts && script >= 0 && prevScript >= 0 && script != prevScript)
+		if i > start && boundary {
+			chunks = append(chunks, chunk{start, i})
+			start = i
+		}
+
+		// script changes are between letters, so spaces and symbols keep the script
+		if script >= 0 || r == '\n' || (!opts.CrossWords && r == ' ') {
+			prevScript = script
+		}
+		prev = r
+		i += size
+	}
+	if start < len(text) {
+		chunks = append(chunks, chunk{start, len(text)})
+	}
+	return chunks
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// scripts told apart by SplitScripts, letters of any other script share one
+var letterScripts = []*unicode.RangeTable{
+	unicode.Han, unicode.Latin, unicode.Cyrillic, unicode.Greek, unicode.Arabic,
+	unicode.Hebrew, unicode.Devanagari, unicode.Thai, unicode.Hangul,
+}
+
+// letterScript returns the index of the script of r in letterScripts, -1 if r
+// is not a letter
+func letterScript(r rune) int {
+	if !unicode.IsLetter(r) {
+		return -1
+	}
+	if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+		return 0 // Japanese mixes kanji and kana in one word
+	}
+	for i, table := range letterScripts {
+		if unicode.Is(table, r) {
+			return i
+		}
+	}
+	return len(letterScripts)
+}
+
+// normalize returns the text that is encoded, with the dummy prefix in
+// SentencePiece mode
+func (bpe *BPETokenizer) normalize(text string) string {
+	if bpe.SentencePiece != nil && bpe.SentencePiece.DummyPrefix && text != "" {
+		return " " + text
+	}
+	return text
+}
+
+// chunks splits normalized text into the chunks merges stay within, the whole
+// text for CrossChunks models
+func (bpe *BPETokenizer) chunks(text string) []chunk {
+	if bpe.CrossChunks {
+		if text == "" {
+			return nil
+		}
+		return []chunk{{0, len(text)}}
+	}
+	if bpe.SentencePiece != nil {
+		return splitSentencePiece(text, *bpe.SentencePiece)
+	}
+	return splitChunks(text)
+}
+
+// trimDummyPrefix removes the space the dummy prefix added in front of decoded text
+func trimDummyPrefix[S string | []byte](s S, opts *SentencePieceOptions) S {
+	if opts != nil && opts.DummyPrefix && len(s) > 0 && s[0] == ' ' {
+		return s[1:]
+	}
+	return s
+}
+
+// Piece returns the token of id as text, with spaces shown as ▁ in
+// SentencePiece mode, and "" if id is not in the vocabulary
+func (bpe *BPETokenizer) Piece(id int) string {
+	token := string(bpe.table().token(id))
+	if bpe.SentencePiece != nil {
+		return strings.ReplaceAll(token, " ", SPACE_MARKER)
+	}
+	return token
+}
@@ -0,0 +1,459 @@
+package bpe
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// PieceType is the type of a piece in a SentencePiece model
+type PieceType int32
+
+const (
+	PieceNormal      PieceType = 1 // merged from characters by BPE
+	PieceUnknown     PieceType = 2 // stands for text no other piece covers
+	PieceControl     PieceType = 3 // <s>, </s> and the like, decoded as nothing
+	PieceUserDefined PieceType = 4 // always one token where it appears in the text
+	PieceUnused      PieceType = 5 // in the vocabulary but never produced
+	PieceByte        PieceType = 6 // <0xNN> byte fallback token
+)
+
+// SentencePiece is one piece of a SentencePiece model
+type SentencePiece struct {
+	Piece string
+	Score float32
+	Type  PieceType
+}
+
+/**
+ * SentencePieceModel encodes text with a BPE .model file of SentencePiece, as
+ * shipped with Llama-family checkpoints, following the steps of the library
+ * 1. Spaces become ▁, with a dummy prefix and extra spaces removed when the
+ *    model asks for it. Precompiled normalization rules (e.g. nmt_nfkc) are
+ *    not applied, Normalizer names them so callers can tell
+ * 2. Text starts as characters, user-defined pieces as one symbol each
+ * 3. The adjacent pair forming the piece with the highest score is merged,
+ *    the leftmost one on ties, until no pair forms a piece
+ * 4. Symbols that are no piece fall back to <0xNN> byte pieces, or to the
+ *    unknown piece
+**/
+type SentencePieceModel struct {
+	Pieces                 []SentencePiece
+	ByteFallback           bool
+	AddDummyPrefix         bool
+	RemoveExtraWhitespaces bool
+	EscapeWhitespaces      bool
+	Normalizer             string
+	UnkID                  int
+
+	vocab       *vocabulary    // decoded bytes of every piece, spaces instead of ▁
+	ids         map[string]int // decoded normal, user-defined and unused pieces
+	userDefined []string       // decoded user-defined pieces, longest first
+	byteIDs     [256]int       // id of the <0xNN> piece of every byte, -1 if missing
+}
+
+// SentencePiece model types, only BPE models can be loaded
+const (
+	spmModelUnigram = 1
+	spmModelBPE     = 2
+)
+
+// LoadSentencePieceFile loads a SentencePiece .model file
+func LoadSentencePieceFile(path string) (*SentencePieceModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	model, err := LoadSentencePiece(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return model, nil
+}
+
+/**
+ * Load a SentencePiece model from its serialized ModelProto
+ * 1. Read the pieces, the trainer spec and the normalizer spec, with the
+ *    defaults of sentencepiece_model.proto for missing fields
+ * 2. Reject models that are not BPE
+ * 3. Build the lookups used by Encode and the vocabulary used by Decode
+**/
+func LoadSentencePiece(data []byte) (*SentencePieceModel, error) {
+	model := &SentencePieceModel{
+		AddDummyPrefix:         true,
+		RemoveExtraWhitespaces: true,
+		EscapeWhitespaces:      true,
+	}
+	modelType := uint64(spmModelUnigram)
+	unkSurface := " ⁇ "
+
+	err := parseProtoFields(data, func(f protoField) error {
+		switch f.num {
+		case 1: // pieces
+			piece, err := parseSentencePiece(f)
+			model.Pieces = append(model.Pieces, piece)
+			return err
+		case 2: // trainer_spec
+			return parseProtoFields(f.message(), func(f protoField) error {
+				switch f.num {
+				case 3:
+					modelType = f.varint
+				case 35:
+					model.ByteFallback = f.varint != 0
+				case 40:
+					model.UnkID = int(int32(f.varint))
+				case 44:
+					unkSurface = string(f.bytes)
+				}
+				return nil
+			})
+		case 3: // normalizer_spec
+			return parseProtoFields(f.message(), func(f protoField) error {
+				switch f.num {
+				case 1:
+					model.Normalizer = string(f.bytes)
+				case 3:
+					model.AddDummyPrefix = f.varint != 0
+				case 4:
+					model.RemoveExtraWhitespaces = f.varint != 0
+				case 5:
+					model.EscapeWhitespaces = f.varint != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sentencepiece model: %w", err)
+	}
+
+	if modelType != spmModelBPE {
+		return nil, fmt.Errorf("sentencepiece model: type %d is not BPE (%d)", modelType, spmModelBPE)
+	}
+	if len(model.Pieces) == 0 {
+		return nil, errors.New("sentencepiece model: no pieces")
+	}
+	if model.UnkID < 0 || model.UnkID >= len(model.Pieces) {
+		return nil, fmt.Errorf("sentencepiece model: unknown id %d outside the vocabulary of %d", model.UnkID, len(model.Pieces))
+	}
+
+	model.build(unkSurface)
+	return model, nil
+}
+
+// parseSentencePiece parses a ModelProto.SentencePiece message
+func parseSentencePiece(f protoField) (SentencePiece, error) {
+	piece := SentencePiece{Type: PieceNormal}
+	if f.typ != protowire.BytesType {
+		return piece, fmt.Errorf("piece has wire type %d", f.typ)
+	}
+	err := parseProtoFields(f.bytes, func(f protoField) error {
+		switch f.num {
+		case 1:
+			piece.Piece = string(f.bytes)
+		case 2:
+			piece.Score = math.Float32frombits(uint32(f.varint))
+		case 3:
+			piece.Type = PieceType(f.varint)
+		}
+		return nil
+	})
+	return piece, err
+}
+
+// build fills the lookups and the vocabulary from Pieces
+func (m *SentencePieceModel) build(unkSurface string) {
+	m.ids = make(map[string]int)
+	for i := range m.byteIDs {
+		m.byteIDs[i] = -1
+	}
+
+	surfaces := make([]string, len(m.Pieces))
+	for id, p := range m.Pieces {
+		switch p.Type {
+		case PieceNormal, PieceUserDefined, PieceUnused:
+			surfaces[id] = m.unescape(p.Piece)
+			if _, dup := m.ids[surfaces[id]]; !dup {
+				m.ids[surfaces[id]] = id
+			}
+			if p.Type == PieceUserDefined {
+				m.userDefined = append(m.userDefined, surfaces[id])
+			}
+		case PieceByte:
+			var b byte
+			if _, err := fmt.Sscanf(p.Piece, "<0x%02X>", &b); err == nil {
+				surfaces[id] = string([]byte{b})
+				m.byteIDs[b] = id
+			}
+		case PieceUnknown:
+			surfaces[id] = unkSurface
+		}
+	}
+	sort.Slice(m.userDefined, func(i, j int) bool { return len(m.userDefined[i]) > len(m.userDefined[j]) })
+
+	m.vocab = &vocabulary{offsets: make([]uint32, len(surfaces)+1)}
+	for id, s := range surfaces {
+		m.vocab.arena = append(m.vocab.arena, s...)
+		m.vocab.offsets[id+1] = uint32(len(m.vocab.arena))
+	}
+}
+
+// unescape turns ▁ back into the space it stands for
+func (m *SentencePieceModel) unescape(piece string) string {
+	if m.EscapeWhitespaces {
+		return strings.ReplaceAll(piece, SPACE_MARKER, " ")
+	}
+	return piece
+}
+
+// VocabSize returns the number of pieces
+func (m *SentencePieceModel) VocabSize() int {
+	return len(m.Pieces)
+}
+
+// Piece returns the piece of id as stored in the model, "" if id is out of range
+func (m *SentencePieceModel) Piece(id int) string {
+	if id < 0 || id >= len(m.Pieces) {
+		return ""
+	}
+	return m.Pieces[id].Piece
+}
+
+// PieceToID returns the id of a normal, user-defined or unused piece given with ▁
+func (m *SentencePieceModel) PieceToID(piece string) (int, bool) {
+	id, ok := m.ids[m.unescape(piece)]
+	return id, ok
+}
+
+// normalize applies the whitespace rules of the model, in the decoded form
+// where a space stands for ▁
+func (m *SentencePieceModel) normalize(text string) string {
+	if m.EscapeWhitespaces {
+		text = strings.ReplaceAll(text, SPACE_MARKER, " ")
+	}
+	if m.RemoveExtraWhitespaces {
+		text = strings.Join(strings.FieldsFunc(text, func(r rune) bool { return r == ' ' }), " ")
+	}
+	if m.AddDummyPrefix && text != "" {
+		text = " " + text
+	}
+	return text
+}
+
+// spmSymbol is a span of the normalized text in the linked list of symbols
+type spmSymbol struct {
+	start, end int
+	prev, next int  // -1 at the ends
+	frozen     bool // user-defined pieces are never merged
+}
+
+// spmPair is a candidate merge of two adjacent symbols
+type spmPair struct {
+	left, right int
+	size        int // bytes of the merged piece, to detect stale pairs
+	score       float32
+	start       int
+}
+
+type spmPairHeap []spmPair
+
+func (h spmPairHeap) Len() int { return len(h) }
+func (h spmPairHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score > h[j].score
+	}
+	return h[i].start < h[j].start
+}
+func (h spmPairHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *spmPairHeap) Push(x any)   { *h = append(*h, x.(spmPair)) }
+func (h *spmPairHeap) Pop() any {
+	old := *h
+	pair := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return pair
+}
+
+/**
+ * Encode text into piece ids
+ * 1. Normalize the whitespace and split into characters and user-defined pieces
+ * 2. Merge the best scoring adjacent pair until no pair forms a piece,
+ *    remembering how each piece was made
+ * 3. Emit each symbol, splitting unused pieces back into their parts and
+ *    falling back to bytes or the unknown piece, runs of unknown pieces become one
+**/
+func (m *SentencePieceModel) Encode(text string) []int {
+	text = m.normalize(text)
+	ids := []int{}
+	if text == "" {
+		return ids
+	}
+
+	var symbols []spmSymbol
+	for i := 0; i < len(text); {
+		size, frozen := 0, false
+		for _, piece := range m.userDefined {
+			if strings.HasPrefix(text[i:], piece) {
+				size, frozen = len(piece), true
+				break
+			}
+		}
+		if size == 0 {
+			_, size = utf8.DecodeRuneInString(text[i:])
+		}
+		symbols = append(symbols, spmSymbol{start: i, end: i + size, prev: len(symbols) - 1, next: len(symbols) + 1, frozen: frozen})
+		i += size
+	}
+	symbols[len(symbols)-1].next = -1
+
+	pairs := &spmPairHeap{}
+	addPair := func(left, right int) {
+		if left < 0 || right < 0 || symbols[left].frozen || symbols[right].frozen {
+			return
+		}
+		id, ok := m.ids[text[symbols[left].start:symbols[right].end]]
+		if !ok {
+			return
+		}
+		heap.Push(pairs, spmPair{left, right, symbols[right].end - symbols[left].start, m.Pieces[id].Score, symbols[left].start})
+	}
+	for i := 1; i < len(symbols); i++ {
+		addPair(i-1, i)
+	}
+
+	parts := make(map[string][2]string) // merged piece -> its two parts
+	for pairs.Len() > 0 {
+		pair := heap.Pop(pairs).(spmPair)
+		left, right := &symbols[pair.left], &symbols[pair.right]
+		if left.end == left.start || right.end == right.start || left.next != pair.right || right.end-left.start != pair.size {
+			continue // one of the symbols was merged since
+		}
+		parts[text[left.start:right.end]] = [2]string{text[left.start:left.end], text[right.start:right.end]}
+
+		left.end = right.end
+		left.next = right.next
+		if right.next >= 0 {
+			symbols[right.next].prev = pair.left
+		}
+		right.start, right.end = 0, 0
+		addPair(left.prev, pair.left)
+		addPair(pair.left, left.next)
+	}
+
+	var emit func(piece string)
+	emit = func(piece string) {
+		if id, ok := m.ids[piece]; ok {
+			if m.Pieces[id].Type != PieceUnused {
+				ids = append(ids, id)
+				return
+			}
+			if p, ok := parts[piece]; ok {
+				emit(p[0])
+				emit(p[1])
+				return
+			}
+		}
+		if m.ByteFallback {
+			for i := 0; i < len(piece); i++ {
+				if id := m.byteIDs[piece[i]]; id >= 0 {
+					ids = append(ids, id)
+				} else {
+					ids = append(ids, m.UnkID)
+				}
+			}
+			return
+		}
+		if len(ids) == 0 || ids[len(ids)-1] != m.UnkID {
+			ids = append(ids, m.UnkID)
+		}
+	}
+	for i := 0; i >= 0; i = symbols[i].next {
+		emit(text[symbols[i].start:symbols[i].end])
+	}
+	return ids
+}
+
+// Decode ids into text, skipping ids outside the vocabulary
+func (m *SentencePieceModel) Decode(ids []int) string {
+	text, _ := m.DecodeWithOptions(ids, DecodeOptions{})
+	return text
+}
+
+/**
+ * Decode ids into text with options
+ * 1. Concatenate the pieces, ▁ as space, bytes as they are and control pieces
+ *    as nothing, skipping or failing on ids outside the vocabulary
+ * 2. Drop the space of the dummy prefix
+ * 3. Apply the UTF-8 policy, which matters for byte fallback pieces
+**/
+func (m *SentencePieceModel) DecodeWithOptions(ids []int, opts DecodeOptions) (string, error) {
+	var raw []byte
+	for i, id := range ids {
+		if id < 0 || id >= m.vocab.size() {
+			if opts.Strict {
+				return "", &UnknownTokenError{ID: id, Position: i}
+			}
+			continue
+		}
+		raw = append(raw, m.vocab.token(id)...)
+	}
+	raw = trimDummyPrefix(raw, &SentencePieceOptions{DummyPrefix: m.AddDummyPrefix})
+	return ApplyUTF8Policy(raw, opts.UTF8), nil
+}
+
+// protoField is one field of a protobuf message
+type protoField struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64 // value of varint and fixed32 fields
+	bytes  []byte // value of length-delimited fields
+}
+
+// message returns the bytes of an embedded message, nil for other wire types
+func (f protoField) message() []byte {
+	if f.typ != protowire.BytesType {
+		return nil
+	}
+	return f.bytes
+}
+
+// parseProtoFields calls fn with every field of a protobuf message in order
+func parseProtoFields(data []byte, fn func(protoField) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		f := protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(data)
+			f.varint = uint64(v)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
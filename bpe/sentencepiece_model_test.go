@@ -0,0 +1,196 @@
+package bpe
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var update = flag.Bool("update", false, "rewrite the SentencePiece fixture")
+
+const SENTENCEPIECE_FIXTURE = "testdata/sentencepiece_bpe.model"
+
+// spmFixturePieces are laid out like a Llama tokenizer: <unk>, <s>, </s>, the
+// 256 byte pieces, then merges and characters scored by rank. The fixture is
+// built here and not trained by spm_train, so the expected ids check the
+// algorithm as read from bpe_model.cc, not the output of the library.
+func spmFixturePieces() []SentencePiece {
+	pieces := []SentencePiece{
+		{"<unk>", 0, PieceUnknown},
+		{"<s>", 0, PieceControl},
+		{"</s>", 0, PieceControl},
+	}
+	for b := 0; b < 256; b++ {
+		pieces = append(pieces, SentencePiece{fmt.Sprintf("<0x%02X>", b), 0, PieceByte})
+	}
+	for i, piece := range []string{"▁t", "he", "▁the", "▁c", "at", "▁cat", "is", "日本"} {
+		pieces = append(pieces, SentencePiece{piece, -float32(i), PieceNormal}) // ids 259-266
+	}
+	pieces = append(pieces, SentencePiece{"<sep>", 0, PieceUserDefined}) // 267
+	for i, piece := range []string{"▁", "t", "h", "e", "c", "a", "i", "s", "日", "本"} {
+		pieces = append(pieces, SentencePiece{piece, -float32(8 + i), PieceNormal}) // ids 268-277
+	}
+	return pieces
+}
+
+// buildSentencePieceModel serializes a ModelProto the way the SentencePiece trainer does
+func buildSentencePieceModel(pieces []SentencePiece, modelType int, byteFallback, removeExtraWhitespaces bool) []byte {
+	boolValue := func(b bool) uint64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	var data []byte
+	for _, p := range pieces {
+		var piece []byte
+		piece = protowire.AppendTag(piece, 1, protowire.BytesType)
+		piece = protowire.AppendString(piece, p.Piece)
+		piece = protowire.AppendTag(piece, 2, protowire.Fixed32Type)
+		piece = protowire.AppendFixed32(piece, math.Float32bits(p.Score))
+		if p.Type != PieceNormal {
+			piece = protowire.AppendTag(piece, 3, protowire.VarintType)
+			piece = protowire.AppendVarint(piece, uint64(p.Type))
+		}
+		data = protowire.AppendTag(data, 1, protowire.BytesType)
+		data = protowire.AppendBytes(data, piece)
+	}
+
+	var trainer []byte
+	trainer = protowire.AppendTag(trainer, 3, protowire.VarintType)
+	trainer = protowire.AppendVarint(trainer, uint64(modelType))
+	trainer = protowire.AppendTag(trainer, 4, protowire.VarintType)
+	trainer = protowire.AppendVarint(trainer, uint64(len(pieces)))
+	trainer = protowire.AppendTag(trainer, 35, protowire.VarintType)
+	trainer = protowire.AppendVarint(trainer, boolValue(byteFallback))
+	data = protowire.AppendTag(data, 2, protowire.BytesType)
+	data = protowire.AppendBytes(data, trainer)
+
+	var normalizer []byte
+	normalizer = protowire.AppendTag(normalizer, 1, protowire.BytesType)
+	normalizer = protowire.AppendString(normalizer, "identity")
+	normalizer = protowire.AppendTag(normalizer, 3, protowire.VarintType)
+	normalizer = protowire.AppendVarint(normalizer, 1)
+	normalizer = protowire.AppendTag(normalizer, 4, protowire.VarintType)
+	normalizer = protowire.AppendVarint(normalizer, boolValue(removeExtraWhitespaces))
+	data = protowire.AppendTag(data, 3, protowire.BytesType)
+	data = protowire.AppendBytes(data, normalizer)
+
+	return data
+}
+
+func TestSentencePieceFixture(t *testing.T) {
+	want := buildSentencePieceModel(spmFixturePieces(), spmModelBPE, true, false)
+	if *update {
+		if err := os.WriteFile(SENTENCEPIECE_FIXTURE, want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(SENTENCEPIECE_FIXTURE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date, rerun with -update", SENTENCEPIECE_FIXTURE)
+	}
+}
+
+func TestSentencePieceEncode(t *testing.T) {
+	model, err := LoadSentencePieceFile(SENTENCEPIECE_FIXTURE)
+	if err != nil {
+		t.Fatalf("LoadSentencePieceFile: %v", err)
+	}
+	if model.VocabSize() != 278 || !model.ByteFallback || !model.AddDummyPrefix || model.RemoveExtraWhitespaces || model.Normalizer != "identity" {
+		t.Fatalf("LoadSentencePieceFile() = %d pieces %+v", model.VocabSize(), model)
+	}
+
+	// ids worked out by hand from bpe_model.cc: best score first, leftmost on ties, byte fallback for the rest
+	tests := []struct {
+		name     string
+		text     string
+		expected []int
+	}{
+		{"empty", "", []int{}},
+		{"merges", "the cat", []int{261, 264}},
+		{"partial merges", "this", []int{259, 270, 265}},
+		{"byte fallback", "on 日本", []int{268, 3 + 'o', 3 + 'n', 268, 266}},
+		{"multi-byte fallback", "語", []int{268, 3 + 0xe8, 3 + 0xaa, 3 + 0x9e}},
+		{"user defined", "a<sep>b", []int{268, 273, 267, 3 + 'b'}},
+		{"leading spaces", "  the", []int{268, 268, 261}},
+		{"literal marker", "▁the", []int{268, 261}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := model.Encode(tt.text)
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.text, ids, tt.expected)
+			}
+			want := strings.ReplaceAll(tt.text, SPACE_MARKER, " ")
+			if got := model.Decode(ids); got != want {
+				t.Errorf("Decode(%v) = %q, want %q", ids, got, want)
+			}
+		})
+	}
+
+	if got := model.Decode([]int{1, 261, 264, 2, 999}); got != "the cat" {
+		t.Errorf("Decode() with control and unknown ids = %q", got)
+	}
+	if _, err := model.DecodeWithOptions([]int{999}, DecodeOptions{Strict: true}); err == nil {
+		t.Error("DecodeWithOptions(Strict): expected an error")
+	}
+	if id, ok := model.PieceToID("▁cat"); !ok || id != 264 || model.Piece(id) != "▁cat" {
+		t.Errorf("PieceToID(▁cat) = %d, %v", id, ok)
+	}
+}
+
+func TestSentencePieceVariants(t *testing.T) {
+	pieces := spmFixturePieces()
+
+	// without byte fallback runs of unknown characters become one <unk>
+	noFallback, err := LoadSentencePiece(buildSentencePieceModel(pieces, spmModelBPE, false, true))
+	if err != nil {
+		t.Fatalf("LoadSentencePiece: %v", err)
+	}
+	if got, want := noFallback.Encode("  the   zzz cat "), []int{261, 268, 0, 264}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode() = %v, want %v", got, want)
+	}
+	if got := noFallback.Decode([]int{261, 0}); got != "the ⁇ " {
+		t.Errorf("Decode() = %q, want %q", got, "the ⁇ ")
+	}
+
+	// an unused piece is split back into the pieces it was merged from
+	unused := append([]SentencePiece(nil), pieces...)
+	unused[264].Type = PieceUnused
+	model, err := LoadSentencePiece(buildSentencePieceModel(unused, spmModelBPE, true, false))
+	if err != nil {
+		t.Fatalf("LoadSentencePiece: %v", err)
+	}
+	if got, want := model.Encode("cat"), []int{262, 263}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode() with unused piece = %v, want %v", got, want)
+	}
+
+	invalid := map[string][]byte{
+		"unigram":   buildSentencePieceModel(pieces, spmModelUnigram, true, false),
+		"no pieces": buildSentencePieceModel(nil, spmModelBPE, true, false),
+		"truncated": buildSentencePieceModel(pieces, spmModelBPE, true, false)[:100],
+		"not proto": []byte("104-101 256\n"),
+	}
+	for name, data := range invalid {
+		if _, err := LoadSentencePiece(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := LoadSentencePieceFile(filepath.Join(t.TempDir(), "missing.model")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
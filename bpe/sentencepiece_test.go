@@ -0,0 +1,151 @@
+package bpe
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSentencePiece(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		opts     SentencePieceOptions
+		expected []string
+	}{
+		{"words", " hello  world", SentencePieceOptions{}, []string{" hello", " ", " world"}},
+		{"no spaces", "東京は日本の首都", SentencePieceOptions{}, []string{"東京は日本の首都"}},
+		{"cross words", " of the\n end", SentencePieceOptions{CrossWords: true}, []string{" of the", "\n", " end"}},
+		{"split digits", "v12.5", SentencePieceOptions{SplitDigits: true}, []string{"v", "1", "2", ".", "5"}},
+		{"split scripts", "東京tokyoです", SentencePieceOptions{SplitScripts: true}, []string{"東京", "tokyo", "です"}},
+		{"symbols keep the script", "ab-cd 日本", SentencePieceOptions{SplitScripts: true}, []string{"ab-cd", " 日本"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range splitSentencePiece(tt.text, tt.opts) {
+				got = append(got, tt.text[c.start:c.end])
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("splitSentencePiece() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSentencePieceOptions(t *testing.T) {
+	all := SentencePieceOptions{DummyPrefix: true, CrossWords: true, SplitDigits: true, SplitScripts: true}
+	for _, opts := range []SentencePieceOptions{{}, {DummyPrefix: true}, {SplitDigits: true, SplitScripts: true}, all} {
+		parsed, err := parseSentencePieceLine(opts.String())
+		if err != nil {
+			t.Fatalf("parseSentencePieceLine(%q): %v", opts.String(), err)
+		}
+		if *parsed != opts {
+			t.Errorf("parseSentencePieceLine(%q) = %+v, want %+v", opts.String(), *parsed, opts)
+		}
+		fromBits, err := sentencePieceFromBits(opts.bits())
+		if err != nil || *fromBits != opts {
+			t.Errorf("sentencePieceFromBits(%#x) = %+v, %v, want %+v", opts.bits(), fromBits, err, opts)
+		}
+	}
+
+	if got, err := ParseSentencePieceOptions("dummy-prefix,split-digits"); err != nil || *got != (SentencePieceOptions{DummyPrefix: true, SplitDigits: true}) {
+		t.Errorf("ParseSentencePieceOptions() = %+v, %v", got, err)
+	}
+	for _, invalid := range []string{"sentencepiece split-words", "sentencepieces"} {
+		if _, err := parseSentencePieceLine(invalid); err == nil {
+			t.Errorf("parseSentencePieceLine(%q): expected an error", invalid)
+		}
+	}
+}
+
+func TestSentencePieceMode(t *testing.T) {
+	corpus := strings.Repeat("東京は日本の首都です。 the cat sat on the mat\n", 20)
+	opts := &SentencePieceOptions{DummyPrefix: true, SplitScripts: true}
+
+	tokenizer := NewBPETokenizer()
+	tokenizer.TrainWithOptions(corpus, TrainOptions{VocabSize: 300, SentencePiece: opts})
+	opts.DummyPrefix = false // the tokenizer keeps its own copy
+	if tokenizer.SentencePiece == nil || !tokenizer.SentencePiece.DummyPrefix {
+		t.Fatalf("SentencePiece = %+v, want the training options", tokenizer.SentencePiece)
+	}
+
+	// words start with their ▁ and no merge crosses one
+	pieces := make(map[string]bool)
+	for _, id := range tokenizer.Encode("the cat 東京") {
+		pieces[tokenizer.Piece(id)] = true
+	}
+	for _, want := range []string{"▁the", "▁cat"} {
+		if !pieces[want] {
+			t.Errorf("Encode() pieces = %v, want %q", pieces, want)
+		}
+	}
+	for _, m := range tokenizer.Merges {
+		token := string(tokenizer.table().token(m.Index))
+		if strings.Contains(strings.TrimPrefix(token, " "), " ") {
+			t.Errorf("merge %v crosses a word boundary: %q", m, token)
+		}
+	}
+
+	// whitespace round-trips, including leading, repeated and unseen spaces
+	texts := []string{"the cat", " the  cat ", "東京\n\nthe\tmat", "", "▁literal marker"}
+	for _, text := range texts {
+		ids := tokenizer.Encode(text)
+		if got := tokenizer.Decode(ids); got != text {
+			t.Errorf("Decode(Encode(%q)) = %q", text, got)
+		}
+		if got, err := tokenizer.DecodeWithOptions(ids, DecodeOptions{Strict: true}); err != nil || got != text {
+			t.Errorf("DecodeWithOptions(Encode(%q)) = %q, %v", text, got, err)
+		}
+		if got := tokenizer.Count(text); got != len(ids) {
+			t.Errorf("Count(%q) = %d, want %d", text, got, len(ids))
+		}
+	}
+
+	// offsets point into the text, not the dummy prefix
+	tokens := tokenizer.EncodeWithOffsets("the cat")
+	if tokens[0].Start != 0 || tokens[len(tokens)-1].End != len("the cat") {
+		t.Errorf("EncodeWithOffsets() = %+v, want spans covering the text", tokens)
+	}
+
+	// both model formats and Freeze keep the mode
+	path := filepath.Join(t.TempDir(), "spm.model")
+	if err := tokenizer.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile: %v", err)
+	}
+	fromText := NewBPETokenizer()
+	if err := fromText.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tokenizer.WriteBinary(&buf); err != nil {
+		t.Fatalf("WriteBinary: %v", err)
+	}
+	fromBinary := NewBPETokenizer()
+	if err := fromBinary.LoadBinary(buf.Bytes()); err != nil {
+		t.Fatalf("LoadBinary: %v", err)
+	}
+
+	text := "the cat sat 日本"
+	ids := tokenizer.Encode(text)
+	encoder := tokenizer.Freeze()
+	for name, got := range map[string][]int{
+		"text":   fromText.Encode(text),
+		"binary": fromBinary.Encode(text),
+		"Freeze": encoder.Encode(text),
+	} {
+		if !reflect.DeepEqual(got, ids) {
+			t.Errorf("%s: Encode() = %v, want %v", name, got, ids)
+		}
+	}
+	if got := encoder.Decode(ids); got != text {
+		t.Errorf("Freeze().Decode() = %q, want %q", got, text)
+	}
+
+	if _, err := MergeModels(tokenizer, NewBPETokenizer(), 0); err == nil {
+		t.Error("MergeModels: expected an error for different pre-tokenization")
+	}
+}
@@ -0,0 +1,60 @@
+package bpe
+
+// tokenTrie holds the bytes of every token, to find all tokens starting at a
+// position of the text in one walk
+type tokenTrie struct {
+	nodes []trieNode
+}
+
+type trieNode struct {
+	next map[byte]int32 // child node of each byte
+	id   int32          // token ending here, -1 if none
+}
+
+// newTokenTrie builds the trie of the tokens of v. A token with the same bytes
+// as a lower id is left out, like a duplicate merge Encode never produces.
+func newTokenTrie(v *vocabulary) *tokenTrie {
+	t := &tokenTrie{nodes: []trieNode{{id: -1}}}
+	for id := 0; id < v.size(); id++ {
+		node := int32(0)
+		for _, b := range v.token(id) {
+			child, ok := t.nodes[node].next[b]
+			if !ok {
+				child = int32(len(t.nodes))
+				t.nodes = append(t.nodes, trieNode{id: -1})
+				if t.nodes[node].next == nil {
+					t.nodes[node].next = make(map[byte]int32)
+				}
+				t.nodes[node].next[b] = child
+			}
+			node = child
+		}
+		if node != 0 && t.nodes[node].id == -1 {
+			t.nodes[node].id = int32(id)
+		}
+	}
+	return t
+}
+
+// walk calls fn with the id and byte length of every token that text starts with, shortest first
+func (t *tokenTrie) walk(text string, fn func(id TokenID, length int)) {
+	node := int32(0)
+	for i := 0; i < len(text); i++ {
+		child, ok := t.nodes[node].next[text[i]]
+		if !ok {
+			return
+		}
+		node = child
+		if id := t.nodes[node].id; id >= 0 {
+			fn(TokenID(id), i+1)
+		}
+	}
+}
+
+// trie returns the token trie of v, building it on first use
+func (v *vocabulary) trie() *tokenTrie {
+	v.trieOnce.Do(func() {
+		v.tokens = newTokenTrie(v)
+	})
+	return v.tokens
+}
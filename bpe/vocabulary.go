@@ -0,0 +1,111 @@
+package bpe
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// TokenID identifies a token in the vocabulary. It is half the size of an
+// int on 64-bit platforms, which matters for large encoded corpora.
+type TokenID uint32
+
+// vocabulary stores the bytes of every token back to back in one arena,
+// instead of one string per token
+type vocabulary struct {
+	arena   []byte
+	offsets []uint32         // bytes of id are arena[offsets[id]:offsets[id+1]], empty for unknown ids
+	chars   map[rune]TokenID // character alphabet, empty in byte mode
+
+	trieOnce sync.Once
+	tokens   *tokenTrie // built on first use by trie
+}
+
+/**
+ * Build the vocabulary of the base bytes, characters and merges
+ * 1. Compute the length of every token, a character is as long as its UTF-8
+ *    encoding and a merge as its two parts
+ * 2. Lay the tokens out in id order and compute their offsets
+ * 3. Fill the arena, each merge copying the bytes of its parts
+**/
+func newVocabulary(chars []Char, merges []Merge) *vocabulary {
+	size := 256
+	for _, c := range chars {
+		size = max(size, c.Index+1)
+	}
+	for _, m := range merges {
+		size = max(size, m.Index+1)
+	}
+
+	lengths := make([]uint32, size)
+	for i := 0; i < 256; i++ {
+		lengths[i] = 1
+	}
+	for _, c := range chars {
+		// single-byte and invalid characters are byte tokens
+		if c.Index >= 0 && utf8.RuneLen(c.Rune) > 1 {
+			lengths[c.Index] = uint32(utf8.RuneLen(c.Rune))
+		}
+	}
+	partLength := func(id int) uint32 {
+		if id < 0 || id >= size {
+			return 0
+		}
+		return lengths[id]
+	}
+	for _, m := range merges {
+		if m.Index >= 0 {
+			lengths[m.Index] = partLength(m.Pair.First) + partLength(m.Pair.Second)
+		}
+	}
+
+	v := &vocabulary{offsets: make([]uint32, size+1), chars: make(map[rune]TokenID, len(chars))}
+	for id, length := range lengths {
+		v.offsets[id+1] = v.offsets[id] + length
+	}
+
+	v.arena = make([]byte, v.offsets[size])
+	for i := 0; i < 256; i++ {
+		v.arena[v.offsets[i]] = byte(i)
+	}
+	for _, c := range chars {
+		if c.Index >= 0 && utf8.RuneLen(c.Rune) > 1 {
+			copy(v.arena[v.offsets[c.Index]:v.offsets[c.Index+1]], string(c.Rune))
+			v.chars[c.Rune] = TokenID(c.Index)
+		}
+	}
+	for _, m := range merges {
+		if m.Index < 0 {
+			continue
+		}
+		// copy is bounded by the span, so malformed merges cannot overflow it
+		dst := v.arena[v.offsets[m.Index]:v.offsets[m.Index+1]]
+		n := copy(dst, v.token(m.Pair.First))
+		copy(dst[n:], v.token(m.Pair.Second))
+	}
+
+	return v
+}
+
+// size returns one past the largest id
+func (v *vocabulary) size() int {
+	return len(v.offsets) - 1
+}
+
+// maxTokenLength returns the byte length of the longest token
+func (v *vocabulary) maxTokenLength() int {
+	longest := uint32(0)
+	for id := 0; id < v.size(); id++ {
+		longest = max(longest, v.offsets[id+1]-v.offsets[id])
+	}
+	return int(longest)
+}
+
+// token returns the bytes of id, nil if it is not in the vocabulary.
+// The slice points into the arena and must not be modified.
+func (v *vocabulary) token(id int) []byte {
+	if id < 0 || id >= v.size() || v.offsets[id] == v.offsets[id+1] {
+		return nil
+	}
+	start, end := v.offsets[id], v.offsets[id+1]
+	return v.arena[start:end:end]
+}
@@ -0,0 +1,93 @@
+package bpe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewVocabulary(t *testing.T) {
+	tests := []struct {
+		name     string
+		merges   []Merge
+		size     int
+		expected map[int]string // id -> token, "" for unknown
+	}{
+		{
+			name:     "base bytes",
+			size:     256,
+			expected: map[int]string{0: "\x00", 'a': "a", 255: "\xff", 256: "", -1: ""},
+		},
+		{
+			name:     "chained merges",
+			merges:   []Merge{{Pair{'h', 'e'}, 256}, {Pair{256, 'l'}, 257}, {Pair{257, 257}, 258}},
+			size:     259,
+			expected: map[int]string{256: "he", 257: "hel", 258: "helhel"},
+		},
+		{
+			name:     "gap in ids",
+			merges:   []Merge{{Pair{'a', 'b'}, 300}},
+			size:     301,
+			expected: map[int]string{256: "", 299: "", 300: "ab"},
+		},
+		{
+			name:     "part defined later",
+			merges:   []Merge{{Pair{'a', 257}, 256}, {Pair{'b', 'c'}, 257}},
+			size:     258,
+			expected: map[int]string{256: "a", 257: "bc"},
+		},
+		{
+			name:     "unknown part",
+			merges:   []Merge{{Pair{'a', 1000}, 256}},
+			size:     257,
+			expected: map[int]string{256: "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVocabulary(nil, tt.merges)
+			if v.size() != tt.size {
+				t.Errorf("size() = %d, want %d", v.size(), tt.size)
+			}
+			for id, want := range tt.expected {
+				got := v.token(id)
+				if want == "" && got != nil {
+					t.Errorf("token(%d) = %q, want nil", id, got)
+				} else if want != "" && string(got) != want {
+					t.Errorf("token(%d) = %q, want %q", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenIDs(t *testing.T) {
+	tokenizer := NewBPETokenizer()
+	tokenizer.Train("hello world hello world\nhéllo 日本語")
+	text := "hello world\nhéllo 日本語 hello"
+
+	want := tokenizer.Encode(text)
+	ids := tokenizer.EncodeIDs(text)
+	if !reflect.DeepEqual(toInts(ids), want) {
+		t.Errorf("EncodeIDs() = %v, want %v", ids, want)
+	}
+	if got := tokenizer.DecodeIDs(ids); got != text {
+		t.Errorf("DecodeIDs() = %q, want %q", got, text)
+	}
+
+	// AppendEncode keeps dst and reuses its capacity
+	buf := make([]TokenID, 1, 256)
+	buf[0] = 7
+	buf = tokenizer.AppendEncode(buf, text)
+	if buf[0] != 7 || !reflect.DeepEqual(buf[1:], ids) || cap(buf) != 256 {
+		t.Errorf("AppendEncode() = %v (cap %d)", buf, cap(buf))
+	}
+
+	encoder := tokenizer.Freeze()
+	if got := encoder.EncodeIDs(text); !reflect.DeepEqual(got, ids) {
+		t.Errorf("Encoder.EncodeIDs() = %v, want %v", got, ids)
+	}
+	if got := encoder.DecodeIDs(ids); got != text {
+		t.Errorf("Encoder.DecodeIDs() = %q, want %q", got, text)
+	}
+}
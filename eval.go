@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bpicori/bpe-tokenizer/bpe"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// modelEvaluation is one column of the eval output
+type modelEvaluation struct {
+	Model string `json:"model"`
+	*bpe.Evaluation
+}
+
+// evaluateModels loads every model and evaluates it on corpus
+func evaluateModels(paths []string, corpus string) ([]modelEvaluation, error) {
+	var evals []modelEvaluation
+	for _, path := range paths {
+		tokenizer := bpe.NewBPETokenizer()
+		if err := tokenizer.LoadFile(path); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		evals = append(evals, modelEvaluation{Model: path, Evaluation: tokenizer.Evaluate(corpus)})
+	}
+	return evals, nil
+}
+
+func writeEvalJSON(w io.Writer, evals []modelEvaluation) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(evals)
+}
+
+/**
+ * Write the evaluations as a table with one column per model
+ * 1. Totals, the tokens EncodeOptimal would save and vocabulary usage
+ * 2. Bytes, characters and words per token for every script seen by any model
+**/
+func writeEvalTable(w io.Writer, evals []modelEvaluation) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	row := func(label string, value func(e *bpe.Evaluation) string) {
+		cells := []string{label}
+		for _, e := range evals {
+			cells = append(cells, value(e.Evaluation))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t")+"\t")
+	}
+	ratio := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+
+	header := []string{"metric"}
+	for _, e := range evals {
+		header = append(header, e.Model)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+
+	row("tokens", func(e *bpe.Evaluation) string { return fmt.Sprint(e.Tokens) })
+	row("optimal tokens", func(e *bpe.Evaluation) string { return fmt.Sprint(e.OptimalTokens) })
+	row("optimal saving %", func(e *bpe.Evaluation) string { return ratio(e.OptimalSaving) })
+	row("bytes/token", func(e *bpe.Evaluation) string { return ratio(e.BytesPerToken) })
+	row("chars/token", func(e *bpe.Evaluation) string { return ratio(e.CharsPerToken) })
+	row("words/token", func(e *bpe.Evaluation) string { return ratio(e.WordsPerToken) })
+	row("tokens/word", func(e *bpe.Evaluation) string { return ratio(e.Fertility) })
+	row("vocab size", func(e *bpe.Evaluation) string { return fmt.Sprint(e.VocabSize) })
+	row("vocab used %", func(e *bpe.Evaluation) string { return ratio(e.VocabUsed) })
+	row("dead tokens", func(e *bpe.Evaluation) string { return fmt.Sprint(len(e.DeadTokens)) })
+
+	for _, script := range bpe.SCRIPTS {
+		seen := false
+		for _, e := range evals {
+			_, ok := e.Scripts[script]
+			seen = seen || ok
+		}
+		if !seen {
+			continue
+		}
+		stats := func(e *bpe.Evaluation) *bpe.ScriptStats {
+			if s, ok := e.Scripts[script]; ok {
+				return s
+			}
+			return &bpe.ScriptStats{}
+		}
+		row(script+" lines", func(e *bpe.Evaluation) string { return fmt.Sprint(stats(e).Lines) })
+		row(script+" bytes/token", func(e *bpe.Evaluation) string { return ratio(stats(e).BytesPerToken) })
+		row(script+" chars/token", func(e *bpe.Evaluation) string { return ratio(stats(e).CharsPerToken) })
+		row(script+" tokens/word", func(e *bpe.Evaluation) string { return ratio(stats(e).Fertility) })
+		row(script+" optimal saving %", func(e *bpe.Evaluation) string { return ratio(stats(e).OptimalSaving) })
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bpicori/bpe-tokenizer/bpe"
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Output formats for token ids, shared by encode and decode so they can be piped
+const (
+	FORMAT_SPACE  = "space"  // ids separated by spaces: 104 101 108
+	FORMAT_JSON   = "json"   // one JSON array: [104,101,108]
+	FORMAT_NDJSON = "ndjson" // one JSON array per input line
+	FORMAT_BINARY = "binary" // little-endian uint32 per id
+)
+
+func checkFormat(format string) error {
+	switch format {
+	case FORMAT_SPACE, FORMAT_JSON, FORMAT_NDJSON, FORMAT_BINARY:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (want space, json, ndjson or binary)", format)
+}
+
+// fatal prints to stderr and exits, keeping stdout clean for pipes
+func fatal(args ...any) {
+	fmt.Fprintln(os.Stderr, args...)
+	os.Exit(1)
+}
+
+// mustLoad loads a text or binary model, binary models are memory-mapped and
+// stay mapped until the process exits
+func mustLoad(tokenizer *bpe.BPETokenizer, path string) {
+	if _, err := tokenizer.MapFile(path); err != nil {
+		fatal("Error loading model:", err)
+	}
+}
+
+// readInput returns text if set, else the contents of file if set, else stdin
+func readInput(text string, file string) ([]byte, error) {
+	if text != "" {
+		return []byte(text), nil
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return io.ReadAll(os.Stdin)
+}
+
+// splitLines splits input into lines for ndjson, ignoring a final newline
+func splitLines(input string) []string {
+	if input == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+}
+
+/**
+ * Write the encoding of input in the given format
+ * ndjson encodes every line on its own, the other formats encode the whole input
+**/
+func writeEncoded(w io.Writer, tokenizer *bpe.BPETokenizer, input string, format string) error {
+	out := bufio.NewWriter(w)
+
+	switch format {
+	case FORMAT_NDJSON:
+		for _, line := range splitLines(input) {
+			data, _ := json.Marshal(tokenizer.Encode(line))
+			out.Write(data)
+			out.WriteByte('\n')
+		}
+
+	case FORMAT_JSON:
+		data, _ := json.Marshal(tokenizer.Encode(input))
+		out.Write(data)
+		out.WriteByte('\n')
+
+	case FORMAT_BINARY:
+		var scratch [4]byte
+		for _, id := range tokenizer.Encode(input) {
+			binary.LittleEndian.PutUint32(scratch[:], uint32(id))
+			out.Write(scratch[:])
+		}
+
+	default:
+		for i, id := range tokenizer.Encode(input) {
+			if i > 0 {
+				out.WriteByte(' ')
+			}
+			out.WriteString(strconv.Itoa(id))
+		}
+		out.WriteByte('\n')
+	}
+
+	return out.Flush()
+}
+
+/**
+ * Parse token ids in the given format
+ * ndjson returns one group per line, the other formats a single group
+**/
+func parseIDs(data []byte, format string) ([][]int, error) {
+	switch format {
+	case FORMAT_NDJSON:
+		var groups [][]int
+		for i, line := range splitLines(string(data)) {
+			var ids []int
+			if err := json.Unmarshal([]byte(line), &ids); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			groups = append(groups, ids)
+		}
+		return groups, nil
+
+	case FORMAT_JSON:
+		var ids []int
+		if err := json.Unmarshal(data, &ids); err != nil {
+			return nil, err
+		}
+		return [][]int{ids}, nil
+
+	case FORMAT_BINARY:
+		if len(data)%4 != 0 {
+			return nil, fmt.Errorf("binary input of %d bytes is not a multiple of 4", len(data))
+		}
+		ids := make([]int, 0, len(data)/4)
+		for i := 0; i < len(data); i += 4 {
+			ids = append(ids, int(binary.LittleEndian.Uint32(data[i:])))
+		}
+		return [][]int{ids}, nil
+
+	default:
+		var ids []int
+		for _, idStr := range strings.Fields(string(data)) {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ID: %s", idStr)
+			}
+			ids = append(ids, id)
+		}
+		return [][]int{ids}, nil
+	}
+}
+
+// decodeGroups decodes every group of ids, ending each with a newline for ndjson
+func decodeGroups(tokenizer *bpe.BPETokenizer, groups [][]int, format string, opts bpe.DecodeOptions) (string, error) {
+	var output strings.Builder
+	for _, ids := range groups {
+		text, err := tokenizer.DecodeWithOptions(ids, opts)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(text)
+		if format == FORMAT_NDJSON {
+			output.WriteByte('\n')
+		}
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bpicori/bpe-tokenizer/bpe"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func newTestTokenizer() *bpe.BPETokenizer {
+	tokenizer := bpe.NewBPETokenizer()
+	tokenizer.Merges = []bpe.Merge{{Pair: bpe.Pair{First: 'h', Second: 'e'}, Index: 256}}
+	return tokenizer
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tokenizer := newTestTokenizer()
+
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		encoded string // "" to skip checking the encoded bytes
+	}{
+		{"space", FORMAT_SPACE, "hello", "256 108 108 111\n"},
+		{"space multi-line", FORMAT_SPACE, "he\nhe 日本\n", ""},
+		{"json", FORMAT_JSON, "hello", "[256,108,108,111]\n"},
+		{"json multi-line", FORMAT_JSON, "he\nhe 日本\n", ""},
+		{"ndjson", FORMAT_NDJSON, "he\nhe 日本\n", "[256]\n[256,32,230,151,165,230,156,172]\n"},
+		{"ndjson empty line", FORMAT_NDJSON, "he\n\nhe\n", "[256]\n[]\n[256]\n"},
+		{"binary", FORMAT_BINARY, "he!", "\x00\x01\x00\x00!\x00\x00\x00"},
+		{"binary multi-line", FORMAT_BINARY, "he\nhe 日本\n", ""},
+		{"empty", FORMAT_SPACE, "", "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeEncoded(&buf, tokenizer, tt.input, tt.format); err != nil {
+				t.Fatalf("writeEncoded() error = %v", err)
+			}
+			if tt.encoded != "" && buf.String() != tt.encoded {
+				t.Errorf("writeEncoded() = %q, want %q", buf.String(), tt.encoded)
+			}
+
+			groups, err := parseIDs(buf.Bytes(), tt.format)
+			if err != nil {
+				t.Fatalf("parseIDs() error = %v", err)
+			}
+			decoded, err := decodeGroups(tokenizer, groups, tt.format, bpe.DecodeOptions{Strict: true})
+			if err != nil {
+				t.Fatalf("decodeGroups() error = %v", err)
+			}
+			if decoded != tt.input {
+				t.Errorf("decode(encode(%q)) = %q", tt.input, decoded)
+			}
+		})
+	}
+}
+
+func TestParseIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		data     string
+		expected [][]int
+		wantErr  bool
+	}{
+		{"space", FORMAT_SPACE, " 1 2\n3 ", [][]int{{1, 2, 3}}, false},
+		{"json", FORMAT_JSON, "[1, 2]", [][]int{{1, 2}}, false},
+		{"ndjson", FORMAT_NDJSON, "[1]\n[2,3]\n", [][]int{{1}, {2, 3}}, false},
+		{"binary", FORMAT_BINARY, "\x01\x00\x00\x00\x00\x00\x01\x00", [][]int{{1, 65536}}, false},
+		{"invalid space", FORMAT_SPACE, "1 x", nil, true},
+		{"invalid json", FORMAT_JSON, "[1,", nil, true},
+		{"invalid ndjson line", FORMAT_NDJSON, "[1]\nx\n", nil, true},
+		{"truncated binary", FORMAT_BINARY, "\x01\x00\x00", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIDs([]byte(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseIDs() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if err := checkFormat("csv"); err == nil {
+		t.Error("checkFormat(csv): expected an error")
+	}
+}
@@ -2,4 +2,15 @@ module bpicori/bpe-tokenizer
 
 go 1.24.4
 
-require github.com/dlclark/regexp2 v1.11.5
+require (
+	github.com/dlclark/regexp2 v1.11.5
+	google.golang.org/grpc v1.73.0
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	golang.org/x/net v0.38.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250324211829-b45e905df463 // indirect
+)
@@ -0,0 +1,213 @@
+// Package grpcserver implements the tokenizerpb.Tokenizer gRPC service.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"bpicori/bpe-tokenizer/bpe"
+	"bpicori/bpe-tokenizer/tokenizerpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server serves one or more named models. Models are frozen Encoders, so
+// calls are handled concurrently without locking.
+type Server struct {
+	tokenizerpb.UnimplementedTokenizerServer
+
+	models       map[string]*bpe.Encoder
+	defaultModel string
+}
+
+// NewServer returns a server for models. defaultModel is used when a request
+// names no model and may be empty if there is a single model.
+func NewServer(models map[string]*bpe.Encoder, defaultModel string) (*Server, error) {
+	if len(models) == 0 {
+		return nil, errors.New("grpcserver: no models")
+	}
+	if defaultModel == "" && len(models) == 1 {
+		for name := range models {
+			defaultModel = name
+		}
+	}
+	if _, ok := models[defaultModel]; defaultModel != "" && !ok {
+		return nil, fmt.Errorf("grpcserver: unknown default model %q", defaultModel)
+	}
+
+	return &Server{models: models, defaultModel: defaultModel}, nil
+}
+
+func (s *Server) Encode(ctx context.Context, req *tokenizerpb.EncodeRequest) (*tokenizerpb.EncodeResponse, error) {
+	encoder, err := s.model(req.GetModel())
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenizerpb.EncodeResponse{Ids: toWire(encoder.Encode(req.GetText()))}, nil
+}
+
+func (s *Server) Decode(ctx context.Context, req *tokenizerpb.DecodeRequest) (*tokenizerpb.DecodeResponse, error) {
+	encoder, err := s.model(req.GetModel())
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := encoder.DecodeWithOptions(fromWire(req.GetIds()), bpe.DecodeOptions{
+		Strict: req.GetStrict(),
+		UTF8:   utf8Policy(req.GetUtf8()),
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &tokenizerpb.DecodeResponse{Text: text}, nil
+}
+
+func (s *Server) Count(ctx context.Context, req *tokenizerpb.CountRequest) (*tokenizerpb.CountResponse, error) {
+	encoder, err := s.model(req.GetModel())
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenizerpb.CountResponse{Count: int64(encoder.Count(req.GetText()))}, nil
+}
+
+func (s *Server) EncodeStream(stream tokenizerpb.Tokenizer_EncodeStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.Encode(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+/**
+ * Decode a stream of ids incrementally
+ * 1. Take the model and options from the first message
+ * 2. Append the bytes of each message's ids to the pending bytes, dropping
+ *    the space of a SentencePiece dummy prefix like Decode
+ * 3. Send everything but a trailing incomplete character, keep that pending
+ * 4. When the client is done, flush the pending bytes
+**/
+func (s *Server) DecodeStream(stream tokenizerpb.Tokenizer_DecodeStreamServer) error {
+	var encoder *bpe.Encoder
+	var first *tokenizerpb.DecodeRequest
+	var pending []byte
+	position := 0
+	dummyPrefix := false // the space of a SentencePiece dummy prefix is still to be dropped
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if first == nil {
+			first = req
+			encoder, err = s.model(req.GetModel())
+			if err != nil {
+				return err
+			}
+			spm := encoder.SentencePiece()
+			dummyPrefix = spm != nil && spm.DummyPrefix
+		}
+
+		for _, id := range req.GetIds() {
+			pending, err = encoder.AppendDecode(pending, []int{int(id)})
+			if err != nil && first.GetStrict() {
+				return status.Error(codes.InvalidArgument, (&bpe.UnknownTokenError{ID: int(id), Position: position}).Error())
+			}
+			position++
+		}
+		// AppendDecode keeps the prefix, drop it like Decode does
+		if dummyPrefix && len(pending) > 0 {
+			if pending[0] == ' ' {
+				pending = pending[1:]
+			}
+			dummyPrefix = false
+		}
+
+		cut := len(pending) - incompleteSuffix(pending)
+		text := bpe.ApplyUTF8Policy(pending[:cut], utf8Policy(first.GetUtf8()))
+		pending = append(pending[:0], pending[cut:]...)
+
+		if err := stream.Send(&tokenizerpb.DecodeResponse{Text: text}); err != nil {
+			return err
+		}
+	}
+
+	if len(pending) > 0 {
+		text := bpe.ApplyUTF8Policy(pending, utf8Policy(first.GetUtf8()))
+		return stream.Send(&tokenizerpb.DecodeResponse{Text: text})
+	}
+	return nil
+}
+
+// model looks up a model by name, falling back to the default model
+func (s *Server) model(name string) (*bpe.Encoder, error) {
+	if name == "" {
+		name = s.defaultModel
+	}
+
+	encoder, ok := s.models[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown model %q", name)
+	}
+	return encoder, nil
+}
+
+// incompleteSuffix returns the length of a trailing character that is cut
+// short but could still become valid once more bytes arrive
+func incompleteSuffix(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return len(b) - i
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+func utf8Policy(p tokenizerpb.UTF8Policy) bpe.UTF8Policy {
+	if p == tokenizerpb.UTF8Policy_UTF8_POLICY_ESCAPE {
+		return bpe.UTF8Escape
+	}
+	return bpe.UTF8Replace
+}
+
+func toWire(ids []int) []uint32 {
+	wire := make([]uint32, len(ids))
+	for i, id := range ids {
+		wire[i] = uint32(id)
+	}
+	return wire
+}
+
+func fromWire(wire []uint32) []int {
+	ids := make([]int, len(wire))
+	for i, id := range wire {
+		ids[i] = int(id)
+	}
+	return ids
+}
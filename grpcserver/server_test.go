@@ -0,0 +1,263 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"bpicori/bpe-tokenizer/bpe"
+	"bpicori/bpe-tokenizer/tokenizerpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T) (tokenizerpb.TokenizerClient, map[string]*bpe.Encoder) {
+	t.Helper()
+
+	english := bpe.NewBPETokenizer()
+	english.Train("hello world hello world")
+	other := bpe.NewBPETokenizer()
+	other.Train("bonjour le monde bonjour")
+	models := map[string]*bpe.Encoder{"en": english.Freeze(), "fr": other.Freeze()}
+
+	return newTestClientWith(t, models, "en"), models
+}
+
+func newTestClientWith(t *testing.T, models map[string]*bpe.Encoder, defaultModel string) tokenizerpb.TokenizerClient {
+	t.Helper()
+
+	s, err := NewServer(models, defaultModel)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	tokenizerpb.RegisterTokenizerServer(grpcServer, s)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return tokenizerpb.NewTokenizerClient(conn)
+}
+
+func TestUnary(t *testing.T) {
+	client, models := newTestClient(t)
+	ctx := context.Background()
+	text := "hello world"
+
+	encoded, err := client.Encode(ctx, &tokenizerpb.EncodeRequest{Text: text})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !reflect.DeepEqual(fromWire(encoded.GetIds()), models["en"].Encode(text)) {
+		t.Errorf("Encode() = %v, want %v", encoded.GetIds(), models["en"].Encode(text))
+	}
+
+	decoded, err := client.Decode(ctx, &tokenizerpb.DecodeRequest{Ids: encoded.GetIds()})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded.GetText() != text {
+		t.Errorf("Decode() = %q, want %q", decoded.GetText(), text)
+	}
+
+	count, err := client.Count(ctx, &tokenizerpb.CountRequest{Model: "fr", Text: "bonjour"})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if int(count.GetCount()) != models["fr"].Count("bonjour") {
+		t.Errorf("Count() = %d, want %d", count.GetCount(), models["fr"].Count("bonjour"))
+	}
+}
+
+func TestUnaryErrors(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.Encode(ctx, &tokenizerpb.EncodeRequest{Model: "de", Text: "hallo"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("unknown model error = %v, want NotFound", err)
+	}
+
+	_, err = client.Decode(ctx, &tokenizerpb.DecodeRequest{Ids: []uint32{104, 99999}, Strict: true})
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "99999") {
+		t.Errorf("strict decode error = %v, want InvalidArgument naming the id", err)
+	}
+
+	decoded, err := client.Decode(ctx, &tokenizerpb.DecodeRequest{
+		Ids:  []uint32{104, 0xC3},
+		Utf8: tokenizerpb.UTF8Policy_UTF8_POLICY_ESCAPE,
+	})
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded.GetText() != `h\xc3` {
+		t.Errorf("escaped Decode() = %q, want %q", decoded.GetText(), `h\xc3`)
+	}
+}
+
+func TestEncodeStream(t *testing.T) {
+	client, models := newTestClient(t)
+
+	stream, err := client.EncodeStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := []string{"hello", " world", "hello world"}
+	for _, text := range texts {
+		if err := stream.Send(&tokenizerpb.EncodeRequest{Text: text}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fromWire(resp.GetIds()), models["en"].Encode(text)) {
+			t.Errorf("EncodeStream(%q) = %v, want %v", text, resp.GetIds(), models["en"].Encode(text))
+		}
+	}
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("expected end of stream, got %v", err)
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	stream, err := client.DecodeStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "é" is 0xC3 0xA9 and "日" is 0xE6 0x97 0xA5, split over several messages
+	messages := [][]uint32{{'h', 0xC3}, {0xA9, ' ', 0xE6}, {0x97}, {0xA5, '!'}}
+	expected := []string{"h", "é ", "", "日!"}
+
+	for i, ids := range messages {
+		if err := stream.Send(&tokenizerpb.DecodeRequest{Ids: ids}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetText() != expected[i] {
+			t.Errorf("message %d decoded to %q, want %q", i, resp.GetText(), expected[i])
+		}
+	}
+
+	// a dangling partial character is flushed with the policy when the client is done
+	if err := stream.Send(&tokenizerpb.DecodeRequest{Ids: []uint32{0xE6}}); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := stream.Recv(); err != nil || resp.GetText() != "" {
+		t.Fatalf("partial character response = %v, %v", resp, err)
+	}
+	stream.CloseSend()
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetText() != "�" {
+		t.Errorf("flushed text = %q, want %q", resp.GetText(), "�")
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("expected end of stream, got %v", err)
+	}
+}
+
+func TestDecodeStreamDummyPrefix(t *testing.T) {
+	tokenizer := bpe.NewBPETokenizer()
+	tokenizer.TrainWithOptions(strings.Repeat("hello world ", 20), bpe.TrainOptions{
+		VocabSize:     270,
+		SentencePiece: &bpe.SentencePieceOptions{DummyPrefix: true},
+	})
+	encoder := tokenizer.Freeze()
+	client := newTestClientWith(t, map[string]*bpe.Encoder{"spm": encoder}, "spm")
+
+	stream, err := client.DecodeStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the prefix space is dropped once, on the first message with bytes
+	ids := encoder.Encode("hello world")
+	var text strings.Builder
+	for _, part := range [][]int{nil, ids[:1], ids[1:]} {
+		req := &tokenizerpb.DecodeRequest{}
+		for _, id := range part {
+			req.Ids = append(req.Ids, uint32(id))
+		}
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text.WriteString(resp.GetText())
+	}
+	if want := encoder.Decode(ids); text.String() != want || want != "hello world" {
+		t.Errorf("streamed text = %q, Decode() = %q, want %q", text.String(), want, "hello world")
+	}
+}
+
+func TestDecodeStreamStrict(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	stream, err := client.DecodeStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream.Send(&tokenizerpb.DecodeRequest{Ids: []uint32{'a'}, Strict: true})
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&tokenizerpb.DecodeRequest{Ids: []uint32{'b', 99999}})
+	_, err = stream.Recv()
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "position 2") {
+		t.Errorf("strict stream error = %v, want InvalidArgument at position 2", err)
+	}
+}
+
+func TestIncompleteSuffix(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected int
+	}{
+		{[]byte("abc"), 0},
+		{[]byte{'a', 0xC3}, 1},
+		{[]byte{'a', 0xE6, 0x97}, 2},
+		{[]byte{0xE6, 0x97, 0xA5}, 0},
+		{[]byte{'a', 0xF0, 0x9F, 0x98}, 3},
+		{[]byte{'a', 0xA9}, 0}, // stray continuation byte can never complete
+		{[]byte{}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := incompleteSuffix(tt.input); got != tt.expected {
+			t.Errorf("incompleteSuffix(%x) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bpicori/bpe-tokenizer/bpe"
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// alternating background colors used to tell neighbouring tokens apart
+var tokenColors = []string{"\x1b[30;46m", "\x1b[30;43m"}
+
+const colorReset = "\x1b[0m"
+
+// visible quotes s without the surrounding quotes, so whitespace, control
+// characters and invalid UTF-8 bytes show up as escapes
+func visible(s string) string {
+	quoted := strconv.Quote(s)
+	return quoted[1 : len(quoted)-1]
+}
+
+/**
+ * Print how text is tokenized
+ * 1. The text with every token highlighted (or bracketed without color)
+ * 2. The pre-tokenizer chunks
+ * 3. One row per token with its id, bytes and text
+ * 4. A summary of characters per token
+**/
+func inspectText(tokenizer *bpe.BPETokenizer, text string, w io.Writer, color bool) {
+	tokens := tokenizer.EncodeWithOffsets(text)
+
+	var line strings.Builder
+	for i, tok := range tokens {
+		piece := visible(text[tok.Start:tok.End])
+		if color {
+			line.WriteString(tokenColors[i%len(tokenColors)] + piece + colorReset)
+		} else {
+			line.WriteString("[" + piece + "]")
+		}
+	}
+	fmt.Fprintln(w, "tokens:", line.String())
+
+	var chunks strings.Builder
+	for i := 0; i < len(tokens); {
+		j := i
+		for j < len(tokens) && tokens[j].Chunk == tokens[i].Chunk {
+			j++
+		}
+		chunks.WriteString("[" + visible(text[tokens[i].Start:tokens[j-1].End]) + "]")
+		i = j
+	}
+	fmt.Fprintln(w, "chunks:", chunks.String())
+
+	for _, tok := range tokens {
+		raw := []byte(text[tok.Start:tok.End])
+		fmt.Fprintf(w, "  %6d  %-24s %q\n", tok.ID, fmt.Sprintf("% x", raw), raw)
+	}
+
+	chars := utf8.RuneCountInString(text)
+	perToken := 0.0
+	if len(tokens) > 0 {
+		perToken = float64(chars) / float64(len(tokens))
+	}
+	fmt.Fprintf(w, "%d chars, %d bytes, %d tokens, %.2f chars/token\n", chars, len(text), len(tokens), perToken)
+}
+
+// runInspect inspects every line read from in, prompting when interactive
+func runInspect(tokenizer *bpe.BPETokenizer, in io.Reader, w io.Writer, interactive bool, color bool) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for {
+		if interactive {
+			fmt.Fprint(w, "> ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		inspectText(tokenizer, scanner.Text(), w, color)
+		if interactive {
+			fmt.Fprintln(w)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInspectText(t *testing.T) {
+	tokenizer := newTestTokenizer()
+
+	var buf bytes.Buffer
+	inspectText(tokenizer, "hello 日", &buf, false)
+	lines := strings.Split(buf.String(), "\n")
+
+	expected := []string{
+		`tokens: [he][l][l][o][ ][\xe6][\x97][\xa5]`,
+		`chunks: [hello][ 日]`,
+	}
+	for i, want := range expected {
+		if lines[i] != want {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want)
+		}
+	}
+	if !strings.Contains(buf.String(), "7 chars, 9 bytes, 8 tokens, 0.88 chars/token") {
+		t.Errorf("inspectText() summary missing in %q", buf.String())
+	}
+
+	buf.Reset()
+	inspectText(tokenizer, "he", &buf, true)
+	if !strings.HasPrefix(buf.String(), "tokens: "+tokenColors[0]+"he"+colorReset+"\n") {
+		t.Errorf("colored tokens = %q", buf.String())
+	}
+}
+
+func TestRunInspect(t *testing.T) {
+	tokenizer := newTestTokenizer()
+
+	var buf bytes.Buffer
+	if err := runInspect(tokenizer, strings.NewReader("he\nhe he\n"), &buf, true, false); err != nil {
+		t.Fatalf("runInspect() error = %v", err)
+	}
+	if got := strings.Count(buf.String(), "> "); got != 3 {
+		t.Errorf("runInspect() printed %d prompts, want 3", got)
+	}
+	if got := strings.Count(buf.String(), "tokens: "); got != 2 {
+		t.Errorf("runInspect() inspected %d lines, want 2", got)
+	}
+}
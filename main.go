@@ -2,15 +2,44 @@ package main
 
 import (
 	"bpicori/bpe-tokenizer/bpe"
+	"bpicori/bpe-tokenizer/grpcserver"
+	"bpicori/bpe-tokenizer/server"
+	"bpicori/bpe-tokenizer/tokenizerpb"
+	"context"
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
 )
 
-func loadTrainingText() string {
-	file, err := os.Open("training_text.txt")
+const TRAINING_FILE = "training_text.txt"
+
+const COMMANDS = `Commands:
+  train    [-file=training_text.txt] [-model=vocab.model] [-char-coverage=0.9995] [-sentencepiece [-sp-rules=dummy-prefix,split-scripts]]
+  encode   [-text="<text>" | -file=<path>] [-format=space|json|ndjson|binary]
+  decode   [-ids="<id1 id2 ...>" | -file=<path>] [-format=space|json|ndjson|binary] [-strict] [-utf8=keep|replace|escape]
+  count    [-text="<text>" | -file=<path>]
+  dataset  -out=<prefix> [-shard-size=N] [-sep=ID] [-workers=N] [-dropout=P -seed=N] <files...>
+  serve    [-addr=:8080] [-model=name=path ...]
+  grpc     [-addr=:9090] [-model=name=path ...]
+  inspect  [-text="<text>"]
+  eval     [-file=<path>] [-format=table|json] [models...]
+  freq     [-file=<path>] [-format=csv|json]
+  convert  -in=<path> -out=<path> [-to=binary|text]
+encode and decode read stdin when no text, ids or file is given.
+Every command except serve, grpc, eval and convert takes -model=<path> (default vocab.model).
+Models can be in the text or the binary format, binary models are memory-mapped.`
+
+func loadTrainingText(path string) string {
+	file, err := os.Open(path)
 	if err != nil {
 		panic(err)
 	}
@@ -32,62 +61,354 @@ func loadTrainingText() string {
 	return string(data)
 }
 
+// modelFlags collects repeated -model name=path flags
+type modelFlags map[string]string
+
+func (m modelFlags) String() string {
+	var pairs []string
+	for name, path := range m {
+		pairs = append(pairs, name+"="+path)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m modelFlags) Set(value string) error {
+	name, path, ok := strings.Cut(value, "=")
+	if !ok || name == "" || path == "" {
+		return fmt.Errorf("expected name=path, got %q", value)
+	}
+	m[name] = path
+	return nil
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// loadEncoders loads and freezes every model, defaulting to ./vocab.model
+func loadEncoders(models modelFlags) (map[string]*bpe.Encoder, error) {
+	if len(models) == 0 {
+		models["default"] = bpe.MODEL_FILE
+	}
+
+	encoders := make(map[string]*bpe.Encoder)
+	for name, path := range models {
+		model := bpe.NewBPETokenizer()
+		// binary models stay mapped for the lifetime of the server
+		if _, err := model.MapFile(path); err != nil {
+			return nil, err
+		}
+		encoders[name] = model.Freeze()
+	}
+	return encoders, nil
+}
 
 func main() {
 	trainCmd := flag.NewFlagSet("train", flag.ExitOnError)
 	encodeCmd := flag.NewFlagSet("encode", flag.ExitOnError)
 	decodeCmd := flag.NewFlagSet("decode", flag.ExitOnError)
+	countCmd := flag.NewFlagSet("count", flag.ExitOnError)
+	datasetCmd := flag.NewFlagSet("dataset", flag.ExitOnError)
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	grpcCmd := flag.NewFlagSet("grpc", flag.ExitOnError)
+	inspectCmd := flag.NewFlagSet("inspect", flag.ExitOnError)
+	evalCmd := flag.NewFlagSet("eval", flag.ExitOnError)
+	freqCmd := flag.NewFlagSet("freq", flag.ExitOnError)
+	convertCmd := flag.NewFlagSet("convert", flag.ExitOnError)
 
+	modelPaths := make(map[string]*string)
+	for _, cmd := range []*flag.FlagSet{trainCmd, encodeCmd, decodeCmd, countCmd, datasetCmd, inspectCmd, freqCmd} {
+		modelPaths[cmd.Name()] = cmd.String("model", bpe.MODEL_FILE, "Model file")
+	}
+
+	trainFile := trainCmd.String("file", TRAINING_FILE, "Training text file")
+	trainSentencePiece := trainCmd.Bool("sentencepiece", false, "Train on the raw text with spaces as the ▁ word marker instead of GPT4 pre-tokenization")
+	trainRules := trainCmd.String("sp-rules", "dummy-prefix,split-scripts", "SentencePiece rules: dummy-prefix, cross-words, split-digits, split-scripts")
+	trainCoverage := trainCmd.Float64("char-coverage", 0, "Give the most frequent characters covering this fraction of the text their own base token, 0 for bytes only")
 	encodeInput := encodeCmd.String("text", "", "Text to encode")
-	decodeInput := decodeCmd.String("ids", "", "Space-separated list of token IDs to decode")
+	encodeFile := encodeCmd.String("file", "", "File to encode (default: stdin)")
+	encodeFormat := encodeCmd.String("format", FORMAT_SPACE, "Output format: space, json, ndjson or binary")
+	decodeInput := decodeCmd.String("ids", "", "Token IDs to decode, in the input format")
+	decodeFile := decodeCmd.String("file", "", "File with token IDs to decode (default: stdin)")
+	decodeFormat := decodeCmd.String("format", FORMAT_SPACE, "Input format: space, json, ndjson or binary")
+	decodeStrict := decodeCmd.Bool("strict", false, "Fail on unknown token IDs instead of skipping them")
+	decodeUTF8 := decodeCmd.String("utf8", "keep", "Invalid UTF-8 handling: keep, replace or escape")
+	countInput := countCmd.String("text", "", "Text to count tokens of")
+	countFile := countCmd.String("file", "", "File to count tokens of (default: stdin)")
+	datasetOut := datasetCmd.String("out", "", "Output prefix for shards and index")
+	datasetShardSize := datasetCmd.Int("shard-size", 0, "Maximum tokens per shard (0 for a single shard)")
+	datasetSeparator := datasetCmd.Int("sep", -1, "Token ID written after every document (-1 for none)")
+	datasetWorkers := datasetCmd.Int("workers", 0, "Number of files encoded in parallel (0 for all CPUs)")
+	datasetDropout := datasetCmd.Float64("dropout", 0, "BPE-dropout probability of skipping each merge (0 for none)")
+	datasetSeed := datasetCmd.Uint64("seed", 0, "Seed of the BPE-dropout random numbers")
+	serveAddr := serveCmd.String("addr", ":8080", "Address to listen on")
+	serveModels := modelFlags{}
+	serveCmd.Var(serveModels, "model", "Model to serve as name=path, repeatable (default: default="+bpe.MODEL_FILE+")")
+	serveDefault := serveCmd.String("default", "", "Model used when a request names none")
+	serveMaxBody := serveCmd.Int64("max-body", server.DEFAULT_MAX_BODY_BYTES, "Maximum request body size in bytes")
+	grpcAddr := grpcCmd.String("addr", ":9090", "Address to listen on")
+	grpcModels := modelFlags{}
+	grpcCmd.Var(grpcModels, "model", "Model to serve as name=path, repeatable (default: default="+bpe.MODEL_FILE+")")
+	grpcDefault := grpcCmd.String("default", "", "Model used when a request names none")
+	inspectInput := inspectCmd.String("text", "", "Text to inspect (default: read lines from stdin)")
+	inspectColor := inspectCmd.String("color", "auto", "Highlight tokens with colors: auto, always or never")
+	evalFile := evalCmd.String("file", "", "Corpus to evaluate on (default: stdin)")
+	evalFormat := evalCmd.String("format", "table", "Output format: table or json")
+	freqFile := freqCmd.String("file", "", "Corpus to count tokens in (default: stdin)")
+	freqFormat := freqCmd.String("format", "csv", "Output format: csv or json")
+	convertIn := convertCmd.String("in", "", "Model to convert, text or binary")
+	convertOut := convertCmd.String("out", "", "Path of the converted model")
+	convertTo := convertCmd.String("to", "", "Output format: binary or text (default: the other format)")
 
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: bpe-tokenizer <command> [arguments]")
-		fmt.Println("Commands: train, encode -text=\"<text>\", decode -ids=\"<id1 id2 ...>\"")
+		fmt.Println(COMMANDS)
 		return
 	}
 
 	command := os.Args[1]
-	bpe := bpe.NewBPETokenizer()
+	tokenizer := bpe.NewBPETokenizer()
 
 	switch command {
 	case "train":
 		trainCmd.Parse(os.Args[2:])
-		trainingText := loadTrainingText()
-		bpe.Train(trainingText)
-		bpe.Save()
-		fmt.Println("Training completed and model saved.")
+		trainingText := loadTrainingText(*trainFile)
+		opts := bpe.TrainOptions{CharacterCoverage: *trainCoverage}
+		if *trainSentencePiece {
+			rules, err := bpe.ParseSentencePieceOptions(*trainRules)
+			if err != nil {
+				fatal(err)
+			}
+			opts.SentencePiece = rules
+		}
+		tokenizer.TrainWithOptions(trainingText, opts)
+		if err := tokenizer.SaveFile(*modelPaths["train"]); err != nil {
+			fatal("Error saving model:", err)
+		}
+		fmt.Println("Training completed and model saved to", *modelPaths["train"])
 
 	case "encode":
 		encodeCmd.Parse(os.Args[2:])
-		if *encodeInput == "" {
-			fmt.Println("Usage: bpe-tokenizer encode -text=\"<text>\"")
-			return
+		if err := checkFormat(*encodeFormat); err != nil {
+			fatal(err)
+		}
+		input, err := readInput(*encodeInput, *encodeFile)
+		if err != nil {
+			fatal("Error reading input:", err)
+		}
+		mustLoad(tokenizer, *modelPaths["encode"])
+		if err := writeEncoded(os.Stdout, tokenizer, string(input), *encodeFormat); err != nil {
+			fatal("Error writing output:", err)
 		}
-		bpe.Load()
-		fmt.Println(bpe.Encode(*encodeInput))
 
 	case "decode":
 		decodeCmd.Parse(os.Args[2:])
-		if *decodeInput == "" {
-			fmt.Println("Usage: bpe-tokenizer decode -ids=\"<id1 id2 ...>\"")
+		if err := checkFormat(*decodeFormat); err != nil {
+			fatal(err)
+		}
+		if *decodeInput != "" && *decodeFormat == FORMAT_BINARY {
+			fatal("-ids cannot be used with -format=binary, use -file or stdin")
+		}
+		policy, err := bpe.ParseUTF8Policy(*decodeUTF8)
+		if err != nil {
+			fatal(err)
+		}
+		data, err := readInput(*decodeInput, *decodeFile)
+		if err != nil {
+			fatal("Error reading input:", err)
+		}
+		groups, err := parseIDs(data, *decodeFormat)
+		if err != nil {
+			fatal("Error parsing IDs:", err)
+		}
+		mustLoad(tokenizer, *modelPaths["decode"])
+		output, err := decodeGroups(tokenizer, groups, *decodeFormat, bpe.DecodeOptions{Strict: *decodeStrict, UTF8: policy})
+		if err != nil {
+			fatal("Error decoding:", err)
+		}
+		// decoded text is written as is so encode | decode round trips,
+		// but a terminal still gets its final newline
+		if isTerminal(os.Stdout) && !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+		os.Stdout.WriteString(output)
+
+	case "count":
+		countCmd.Parse(os.Args[2:])
+		input, err := readInput(*countInput, *countFile)
+		if err != nil {
+			fatal("Error reading input:", err)
+		}
+		mustLoad(tokenizer, *modelPaths["count"])
+		fmt.Println(tokenizer.Count(string(input)))
+
+	case "dataset":
+		datasetCmd.Parse(os.Args[2:])
+		if *datasetOut == "" || datasetCmd.NArg() == 0 {
+			fatal("Usage: bpe-tokenizer dataset -out=<prefix> [-shard-size=N] [-sep=ID] [-workers=N] [-dropout=P -seed=N] <files...>")
+		}
+		mustLoad(tokenizer, *modelPaths["dataset"])
+		index, err := tokenizer.WriteDataset(datasetCmd.Args(), bpe.DatasetOptions{
+			Prefix:       *datasetOut,
+			ShardSize:    *datasetShardSize,
+			Separator:    *datasetSeparator,
+			UseSeparator: *datasetSeparator >= 0,
+			Workers:      *datasetWorkers,
+			Dropout:      *datasetDropout,
+			Seed:         *datasetSeed,
+		})
+		if err != nil {
+			fatal("Error writing dataset:", err)
+		}
+		fmt.Printf("Wrote %d documents to %d %s shards\n", len(index.Documents), len(index.Shards), index.Dtype)
+
+	case "serve":
+		serveCmd.Parse(os.Args[2:])
+		encoders, err := loadEncoders(serveModels)
+		if err != nil {
+			fatal("Error loading model:", err)
+		}
+		srv, err := server.NewServer(encoders, server.Options{DefaultModel: *serveDefault, MaxBodyBytes: *serveMaxBody})
+		if err != nil {
+			fatal(err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		fmt.Println("Serving", serveModels.String(), "on", *serveAddr)
+		if err := srv.ListenAndServe(ctx, *serveAddr, 10*time.Second); err != nil {
+			fatal("Server error:", err)
+		}
+
+	case "grpc":
+		grpcCmd.Parse(os.Args[2:])
+		encoders, err := loadEncoders(grpcModels)
+		if err != nil {
+			fatal("Error loading model:", err)
+		}
+		srv, err := grpcserver.NewServer(encoders, *grpcDefault)
+		if err != nil {
+			fatal(err)
+		}
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fatal("Error listening:", err)
+		}
+		grpcServer := grpc.NewServer()
+		tokenizerpb.RegisterTokenizerServer(grpcServer, srv)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			grpcServer.GracefulStop()
+		}()
+		fmt.Println("Serving", grpcModels.String(), "over gRPC on", *grpcAddr)
+		if err := grpcServer.Serve(listener); err != nil {
+			fatal("Server error:", err)
+		}
+
+	case "inspect":
+		inspectCmd.Parse(os.Args[2:])
+		var color bool
+		switch *inspectColor {
+		case "auto":
+			color = isTerminal(os.Stdout)
+		case "always":
+			color = true
+		case "never":
+			color = false
+		default:
+			fatal("Usage: bpe-tokenizer inspect [-text=\"<text>\"] [-color=auto|always|never]")
+		}
+		mustLoad(tokenizer, *modelPaths["inspect"])
+		if *inspectInput != "" {
+			inspectText(tokenizer, *inspectInput, os.Stdout, color)
 			return
 		}
-		bpe.Load()
-		var ids []int
-		for _, idStr := range strings.Fields(*decodeInput) {
-			var id int
-			_, err := fmt.Sscanf(idStr, "%d", &id)
-			if err != nil {
-				fmt.Println("Invalid ID:", idStr)
-				return
+		if err := runInspect(tokenizer, os.Stdin, os.Stdout, isTerminal(os.Stdin), color); err != nil {
+			fatal("Error reading input:", err)
+		}
+
+	case "eval":
+		evalCmd.Parse(os.Args[2:])
+		if *evalFormat != "table" && *evalFormat != "json" {
+			fatal("unknown format", *evalFormat, "(want table or json)")
+		}
+		paths := evalCmd.Args()
+		if len(paths) == 0 {
+			paths = []string{bpe.MODEL_FILE}
+		}
+		corpus, err := readInput("", *evalFile)
+		if err != nil {
+			fatal("Error reading corpus:", err)
+		}
+		evals, err := evaluateModels(paths, string(corpus))
+		if err != nil {
+			fatal("Error loading model:", err)
+		}
+		if *evalFormat == "json" {
+			err = writeEvalJSON(os.Stdout, evals)
+		} else {
+			err = writeEvalTable(os.Stdout, evals)
+		}
+		if err != nil {
+			fatal("Error writing output:", err)
+		}
+
+	case "freq":
+		freqCmd.Parse(os.Args[2:])
+		if *freqFormat != "csv" && *freqFormat != "json" {
+			fatal("unknown format", *freqFormat, "(want csv or json)")
+		}
+		mustLoad(tokenizer, *modelPaths["freq"])
+		corpus, err := readInput("", *freqFile)
+		if err != nil {
+			fatal("Error reading corpus:", err)
+		}
+		stats := tokenizer.TokenFrequencies(string(corpus))
+		if *freqFormat == "json" {
+			err = stats.WriteJSON(os.Stdout)
+		} else {
+			err = stats.WriteCSV(os.Stdout)
+		}
+		if err != nil {
+			fatal("Error writing output:", err)
+		}
+
+	case "convert":
+		convertCmd.Parse(os.Args[2:])
+		if *convertIn == "" || *convertOut == "" {
+			fatal("Usage: bpe-tokenizer convert -in=<path> -out=<path> [-to=binary|text]")
+		}
+		data, err := os.ReadFile(*convertIn)
+		if err != nil {
+			fatal("Error reading model:", err)
+		}
+		to := *convertTo
+		if to == "" {
+			to = "binary"
+			if bpe.IsBinaryModel(data) {
+				to = "text"
 			}
-			ids = append(ids, id)
 		}
-		fmt.Println(bpe.Decode(ids))
+		if to != "binary" && to != "text" {
+			fatal("unknown format", to, "(want binary or text)")
+		}
+		mustLoad(tokenizer, *convertIn)
+		if to == "binary" {
+			err = tokenizer.SaveBinaryFile(*convertOut)
+		} else {
+			err = tokenizer.SaveFile(*convertOut)
+		}
+		if err != nil {
+			fatal("Error writing model:", err)
+		}
+		fmt.Println("Converted", *convertIn, "to", to, "model", *convertOut)
 
 	default:
 		fmt.Println("Unknown command:", command)
-		fmt.Println("Commands: train, encode -text=\"<text>\", decode -ids=\"<id1 id2 ...>\"")
+		fmt.Println(COMMANDS)
 	}
 }
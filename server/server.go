@@ -0,0 +1,296 @@
+// Package server exposes tokenizer models over HTTP with JSON requests.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"bpicori/bpe-tokenizer/bpe"
+)
+
+const DEFAULT_MAX_BODY_BYTES = 1 << 20
+
+type Options struct {
+	DefaultModel string // model used when a request does not name one
+	MaxBodyBytes int64  // request body limit, 0 uses DEFAULT_MAX_BODY_BYTES
+}
+
+// Server serves one or more named models. Models are frozen Encoders, so
+// requests are handled concurrently without locking.
+type Server struct {
+	models map[string]*bpe.Encoder
+	opts   Options
+	mux    *http.ServeMux
+}
+
+type encodeRequest struct {
+	Model string `json:"model"`
+	Text  string `json:"text"`
+}
+
+type encodeResponse struct {
+	IDs []int `json:"ids"`
+}
+
+type decodeRequest struct {
+	Model  string `json:"model"`
+	IDs    []int  `json:"ids"`
+	Strict bool   `json:"strict"`
+	UTF8   string `json:"utf8"` // keep (default), replace or escape
+
+	// ids from the middle of an encoding keep the leading space of a
+	// SentencePiece dummy-prefix model, see bpe.DecodeOptions
+	KeepDummyPrefix bool `json:"keep_dummy_prefix"`
+}
+
+type decodeResponse struct {
+	Text string `json:"text"`
+}
+
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+type batchRequest struct {
+	Model string   `json:"model"`
+	Texts []string `json:"texts"`
+}
+
+type batchResponse struct {
+	IDs    [][]int `json:"ids"`
+	Counts []int   `json:"counts"`
+}
+
+type vocabResponse struct {
+	ID    int    `json:"id"`
+	Token string `json:"token"` // invalid UTF-8 escaped as \xNN
+	Bytes []byte `json:"bytes"` // raw token bytes, base64 in JSON
+}
+
+type modelsResponse struct {
+	Models  []string `json:"models"`
+	Default string   `json:"default"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewServer(models map[string]*bpe.Encoder, opts Options) (*Server, error) {
+	if len(models) == 0 {
+		return nil, errors.New("server: no models")
+	}
+	if opts.DefaultModel == "" && len(models) == 1 {
+		for name := range models {
+			opts.DefaultModel = name
+		}
+	}
+	if _, ok := models[opts.DefaultModel]; opts.DefaultModel != "" && !ok {
+		return nil, fmt.Errorf("server: unknown default model %q", opts.DefaultModel)
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DEFAULT_MAX_BODY_BYTES
+	}
+
+	s := &Server{models: models, opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /encode", s.handleEncode)
+	s.mux.HandleFunc("POST /decode", s.handleDecode)
+	s.mux.HandleFunc("POST /count", s.handleCount)
+	s.mux.HandleFunc("POST /batch", s.handleBatch)
+	s.mux.HandleFunc("GET /vocab/{id}", s.handleVocab)
+	s.mux.HandleFunc("GET /models", s.handleModels)
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+/**
+ * Listen on addr until ctx is cancelled
+ * 1. Serve requests in the background
+ * 2. On cancellation, stop accepting connections and wait for in-flight
+ *    requests for up to shutdownTimeout
+**/
+func (s *Server) ListenAndServe(ctx context.Context, addr string, shutdownTimeout time.Duration) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleEncode(w http.ResponseWriter, r *http.Request) {
+	var req encodeRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	encoder, ok := s.model(w, req.Model)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, encodeResponse{IDs: encoder.Encode(req.Text)})
+}
+
+func (s *Server) handleDecode(w http.ResponseWriter, r *http.Request) {
+	var req decodeRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	encoder, ok := s.model(w, req.Model)
+	if !ok {
+		return
+	}
+
+	opts := bpe.DecodeOptions{Strict: req.Strict, KeepDummyPrefix: req.KeepDummyPrefix}
+	if req.UTF8 != "" {
+		policy, err := bpe.ParseUTF8Policy(req.UTF8)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.UTF8 = policy
+	}
+
+	text, err := encoder.DecodeWithOptions(req.IDs, opts)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, decodeResponse{Text: text})
+}
+
+func (s *Server) handleCount(w http.ResponseWriter, r *http.Request) {
+	var req encodeRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	encoder, ok := s.model(w, req.Model)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, countResponse{Count: encoder.Count(req.Text)})
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	encoder, ok := s.model(w, req.Model)
+	if !ok {
+		return
+	}
+
+	resp := batchResponse{IDs: make([][]int, len(req.Texts)), Counts: make([]int, len(req.Texts))}
+	for i, text := range req.Texts {
+		resp.IDs[i] = encoder.Encode(text)
+		resp.Counts[i] = len(resp.IDs[i])
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleVocab(w http.ResponseWriter, r *http.Request) {
+	encoder, ok := s.model(w, r.URL.Query().Get("model"))
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid token id %q", r.PathValue("id")))
+		return
+	}
+
+	// the raw token, decoding would drop the space of a SentencePiece word
+	raw := encoder.TokenBytes(id)
+	if raw == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown token id %d", id))
+		return
+	}
+	token := bpe.ApplyUTF8Policy(raw, bpe.UTF8Escape)
+
+	writeJSON(w, http.StatusOK, vocabResponse{ID: id, Token: token, Bytes: raw})
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.models))
+	for name := range s.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeJSON(w, http.StatusOK, modelsResponse{Models: names, Default: s.opts.DefaultModel})
+}
+
+// model looks up a model by name, falling back to the default model
+func (s *Server) model(w http.ResponseWriter, name string) (*bpe.Encoder, bool) {
+	if name == "" {
+		name = s.opts.DefaultModel
+	}
+
+	encoder, ok := s.models[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown model %q", name))
+		return nil, false
+	}
+	return encoder, true
+}
+
+// readJSON decodes the request body into v, writing an error response on failure
+func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", tooLarge.Limit))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
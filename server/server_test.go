@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"bpicori/bpe-tokenizer/bpe"
+)
+
+func newTestServer(t *testing.T, opts Options) (*httptest.Server, map[string]*bpe.Encoder) {
+	t.Helper()
+
+	english := bpe.NewBPETokenizer()
+	english.Train("hello world hello world")
+	other := bpe.NewBPETokenizer()
+	other.Train("bonjour le monde bonjour")
+
+	models := map[string]*bpe.Encoder{"en": english.Freeze(), "fr": other.Freeze()}
+	if opts.DefaultModel == "" {
+		opts.DefaultModel = "en"
+	}
+	s, err := NewServer(models, opts)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, models
+}
+
+func post(t *testing.T, url string, body any, out any) int {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decoding response of %s: %v", url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestEncodeDecode(t *testing.T) {
+	ts, models := newTestServer(t, Options{})
+	text := "hello world"
+
+	var encoded encodeResponse
+	if status := post(t, ts.URL+"/encode", encodeRequest{Text: text}, &encoded); status != http.StatusOK {
+		t.Fatalf("/encode status = %d", status)
+	}
+	if !reflect.DeepEqual(encoded.IDs, models["en"].Encode(text)) {
+		t.Errorf("/encode ids = %v, want %v", encoded.IDs, models["en"].Encode(text))
+	}
+
+	var decoded decodeResponse
+	if status := post(t, ts.URL+"/decode", decodeRequest{IDs: encoded.IDs}, &decoded); status != http.StatusOK {
+		t.Fatalf("/decode status = %d", status)
+	}
+	if decoded.Text != text {
+		t.Errorf("/decode text = %q, want %q", decoded.Text, text)
+	}
+}
+
+func TestNamedModels(t *testing.T) {
+	ts, models := newTestServer(t, Options{})
+	text := "bonjour le monde"
+
+	var encoded encodeResponse
+	post(t, ts.URL+"/encode", encodeRequest{Model: "fr", Text: text}, &encoded)
+	if !reflect.DeepEqual(encoded.IDs, models["fr"].Encode(text)) {
+		t.Errorf("/encode with fr = %v, want %v", encoded.IDs, models["fr"].Encode(text))
+	}
+
+	var errResp errorResponse
+	if status := post(t, ts.URL+"/encode", encodeRequest{Model: "de", Text: text}, &errResp); status != http.StatusNotFound {
+		t.Errorf("unknown model status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	resp, err := http.Get(ts.URL + "/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list modelsResponse
+	json.NewDecoder(resp.Body).Decode(&list)
+	if !reflect.DeepEqual(list.Models, []string{"en", "fr"}) || list.Default != "en" {
+		t.Errorf("/models = %+v", list)
+	}
+}
+
+func TestCountAndBatch(t *testing.T) {
+	ts, models := newTestServer(t, Options{})
+
+	var count countResponse
+	post(t, ts.URL+"/count", encodeRequest{Text: "hello world"}, &count)
+	if count.Count != models["en"].Count("hello world") {
+		t.Errorf("/count = %d, want %d", count.Count, models["en"].Count("hello world"))
+	}
+
+	texts := []string{"hello", "", "world hello"}
+	var batch batchResponse
+	post(t, ts.URL+"/batch", batchRequest{Texts: texts}, &batch)
+	if len(batch.IDs) != len(texts) {
+		t.Fatalf("/batch returned %d results, want %d", len(batch.IDs), len(texts))
+	}
+	for i, text := range texts {
+		if !reflect.DeepEqual(batch.IDs[i], models["en"].Encode(text)) || batch.Counts[i] != len(batch.IDs[i]) {
+			t.Errorf("/batch result %d = %v (%d), want %v", i, batch.IDs[i], batch.Counts[i], models["en"].Encode(text))
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	ts, _ := newTestServer(t, Options{})
+
+	var errResp errorResponse
+	if status := post(t, ts.URL+"/decode", decodeRequest{IDs: []int{104, 99999}, Strict: true}, &errResp); status != http.StatusUnprocessableEntity {
+		t.Errorf("strict decode status = %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(errResp.Error, "99999") {
+		t.Errorf("error %q should name the unknown id", errResp.Error)
+	}
+
+	if status := post(t, ts.URL+"/decode", decodeRequest{IDs: []int{104}, UTF8: "bogus"}, &errResp); status != http.StatusBadRequest {
+		t.Errorf("bad policy status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	var decoded decodeResponse
+	post(t, ts.URL+"/decode", decodeRequest{IDs: []int{104, 0xC3}, UTF8: "escape"}, &decoded)
+	if decoded.Text != `h\xc3` {
+		t.Errorf("escaped decode = %q, want %q", decoded.Text, `h\xc3`)
+	}
+}
+
+func TestVocab(t *testing.T) {
+	ts, _ := newTestServer(t, Options{})
+
+	resp, err := http.Get(ts.URL + "/vocab/104")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var vocab vocabResponse
+	json.NewDecoder(resp.Body).Decode(&vocab)
+	if resp.StatusCode != http.StatusOK || vocab.ID != 104 || vocab.Token != "h" || string(vocab.Bytes) != "h" {
+		t.Errorf("/vocab/104 = %d %+v", resp.StatusCode, vocab)
+	}
+
+	for path, status := range map[string]int{
+		"/vocab/99999":     http.StatusNotFound,
+		"/vocab/abc":       http.StatusBadRequest,
+		"/vocab/1?model=x": http.StatusNotFound,
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, status)
+		}
+	}
+}
+
+func TestSentencePieceModel(t *testing.T) {
+	tokenizer := bpe.NewBPETokenizer()
+	tokenizer.TrainWithOptions(strings.Repeat("hello world ", 20), bpe.TrainOptions{
+		VocabSize:     270,
+		SentencePiece: &bpe.SentencePieceOptions{DummyPrefix: true},
+	})
+	encoder := tokenizer.Freeze()
+	s, err := NewServer(map[string]*bpe.Encoder{"spm": encoder}, Options{DefaultModel: "spm"})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// tokens keep their space, only decoding a whole text drops the dummy prefix
+	hello := encoder.Encode("hello")
+	if len(hello) != 1 {
+		t.Fatalf("Encode(\"hello\") = %v, want one token", hello)
+	}
+	for id, want := range map[int]string{' ': " ", hello[0]: " hello"} {
+		resp, err := http.Get(fmt.Sprintf("%s/vocab/%d", ts.URL, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vocab vocabResponse
+		json.NewDecoder(resp.Body).Decode(&vocab)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || vocab.Token != want || string(vocab.Bytes) != want {
+			t.Errorf("/vocab/%d = %d %+v, want %q", id, resp.StatusCode, vocab, want)
+		}
+	}
+	// " world" from the middle of an encoding keeps its space only when asked to
+	world := encoder.Encode("hello world")[1:]
+	for keep, want := range map[bool]string{false: "world", true: " world"} {
+		var decoded decodeResponse
+		post(t, ts.URL+"/decode", decodeRequest{IDs: world, KeepDummyPrefix: keep}, &decoded)
+		if decoded.Text != want {
+			t.Errorf("/decode keep_dummy_prefix=%v = %q, want %q", keep, decoded.Text, want)
+		}
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	ts, _ := newTestServer(t, Options{MaxBodyBytes: 64})
+
+	var errResp errorResponse
+	big := encodeRequest{Text: strings.Repeat("a", 100)}
+	if status := post(t, ts.URL+"/encode", big, &errResp); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body status = %d, want %d", status, http.StatusRequestEntityTooLarge)
+	}
+
+	resp, err := http.Post(ts.URL+"/encode", "application/json", strings.NewReader("{not json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid JSON status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp, err = http.Get(ts.URL + "/encode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /encode status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestNewServerErrors(t *testing.T) {
+	if _, err := NewServer(nil, Options{}); err == nil {
+		t.Error("expected error without models")
+	}
+
+	models := map[string]*bpe.Encoder{"a": bpe.NewBPETokenizer().Freeze(), "b": bpe.NewBPETokenizer().Freeze()}
+	if _, err := NewServer(models, Options{DefaultModel: "c"}); err == nil {
+		t.Error("expected error for unknown default model")
+	}
+}
+
+func TestListenAndServeShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	s, err := NewServer(map[string]*bpe.Encoder{"m": bpe.NewBPETokenizer().Freeze()}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServe(ctx, addr, time.Second)
+	}()
+
+	// wait until the server accepts requests
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		resp, err = http.Get("http://" + addr + "/models")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("server did not start: %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ListenAndServe() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
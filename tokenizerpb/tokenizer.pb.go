@@ -0,0 +1,474 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: tokenizerpb/tokenizer.proto
+
+package tokenizerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// UTF8Policy decides what happens to bytes that are not valid UTF-8. Proto
+// strings must be valid UTF-8, so invalid bytes are never kept as they are.
+type UTF8Policy int32
+
+const (
+	UTF8Policy_UTF8_POLICY_REPLACE UTF8Policy = 0 // replace each invalid byte with U+FFFD
+	UTF8Policy_UTF8_POLICY_ESCAPE  UTF8Policy = 1 // write each invalid byte as \xNN
+)
+
+// Enum value maps for UTF8Policy.
+var (
+	UTF8Policy_name = map[int32]string{
+		0: "UTF8_POLICY_REPLACE",
+		1: "UTF8_POLICY_ESCAPE",
+	}
+	UTF8Policy_value = map[string]int32{
+		"UTF8_POLICY_REPLACE": 0,
+		"UTF8_POLICY_ESCAPE":  1,
+	}
+)
+
+func (x UTF8Policy) Enum() *UTF8Policy {
+	p := new(UTF8Policy)
+	*p = x
+	return p
+}
+
+func (x UTF8Policy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (UTF8Policy) Descriptor() protoreflect.EnumDescriptor {
+	return file_tokenizerpb_tokenizer_proto_enumTypes[0].Descriptor()
+}
+
+func (UTF8Policy) Type() protoreflect.EnumType {
+	return &file_tokenizerpb_tokenizer_proto_enumTypes[0]
+}
+
+func (x UTF8Policy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use UTF8Policy.Descriptor instead.
+func (UTF8Policy) EnumDescriptor() ([]byte, []int) {
+	return file_tokenizerpb_tokenizer_proto_rawDescGZIP(), []int{0}
+}
+
+type EncodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"` // empty selects the default model
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncodeRequest) Reset() {
+	*x = EncodeRequest{}
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeRequest) ProtoMessage() {}
+
+func (x *EncodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeRequest.ProtoReflect.Descriptor instead.
+func (*EncodeRequest) Descriptor() ([]byte, []int) {
+	return file_tokenizerpb_tokenizer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EncodeRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *EncodeRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type EncodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []uint32               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncodeResponse) Reset() {
+	*x = EncodeResponse{}
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeResponse) ProtoMessage() {}
+
+func (x *EncodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeResponse.ProtoReflect.Descriptor instead.
+func (*EncodeResponse) Descriptor() ([]byte, []int) {
+	return file_tokenizerpb_tokenizer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EncodeResponse) GetIds() []uint32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type DecodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"` // empty selects the default model
+	Ids           []uint32               `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Strict        bool                   `protobuf:"varint,3,opt,name=strict,proto3" json:"strict,omitempty"` // fail on unknown ids instead of skipping them
+	Utf8          UTF8Policy             `protobuf:"varint,4,opt,name=utf8,proto3,enum=bpe.tokenizer.v1.UTF8Policy" json:"utf8,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecodeRequest) Reset() {
+	*x = DecodeRequest{}
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeRequest) ProtoMessage() {}
+
+func (x *DecodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeRequest.ProtoReflect.Descriptor instead.
+func (*DecodeRequest) Descriptor() ([]byte, []int) {
+	return file_tokenizerpb_tokenizer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DecodeRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *DecodeRequest) GetIds() []uint32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *DecodeRequest) GetStrict() bool {
+	if x != nil {
+		return x.Strict
+	}
+	return false
+}
+
+func (x *DecodeRequest) GetUtf8() UTF8Policy {
+	if x != nil {
+		return x.Utf8
+	}
+	return UTF8Policy_UTF8_POLICY_REPLACE
+}
+
+type DecodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecodeResponse) Reset() {
+	*x = DecodeResponse{}
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeResponse) ProtoMessage() {}
+
+func (x *DecodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeResponse.ProtoReflect.Descriptor instead.
+func (*DecodeResponse) Descriptor() ([]byte, []int) {
+	return file_tokenizerpb_tokenizer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DecodeResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type CountRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"` // empty selects the default model
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountRequest) Reset() {
+	*x = CountRequest{}
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountRequest) ProtoMessage() {}
+
+func (x *CountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
+func (*CountRequest) Descriptor() ([]byte, []int) {
+	return file_tokenizerpb_tokenizer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CountRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *CountRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type CountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tokenizerpb_tokenizer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_tokenizerpb_tokenizer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CountResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_tokenizerpb_tokenizer_proto protoreflect.FileDescriptor
+
+const file_tokenizerpb_tokenizer_proto_rawDesc = "" +
+	"\n" +
+	"\x1btokenizerpb/tokenizer.proto\x12\x10bpe.tokenizer.v1\"9\n" +
+	"\rEncodeRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"\"\n" +
+	"\x0eEncodeResponse\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\rR\x03ids\"\x81\x01\n" +
+	"\rDecodeRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x10\n" +
+	"\x03ids\x18\x02 \x03(\rR\x03ids\x12\x16\n" +
+	"\x06strict\x18\x03 \x01(\bR\x06strict\x120\n" +
+	"\x04utf8\x18\x04 \x01(\x0e2\x1c.bpe.tokenizer.v1.UTF8PolicyR\x04utf8\"$\n" +
+	"\x0eDecodeResponse\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"8\n" +
+	"\fCountRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"%\n" +
+	"\rCountResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count*=\n" +
+	"\n" +
+	"UTF8Policy\x12\x17\n" +
+	"\x13UTF8_POLICY_REPLACE\x10\x00\x12\x16\n" +
+	"\x12UTF8_POLICY_ESCAPE\x10\x012\x9d\x03\n" +
+	"\tTokenizer\x12K\n" +
+	"\x06Encode\x12\x1f.bpe.tokenizer.v1.EncodeRequest\x1a .bpe.tokenizer.v1.EncodeResponse\x12K\n" +
+	"\x06Decode\x12\x1f.bpe.tokenizer.v1.DecodeRequest\x1a .bpe.tokenizer.v1.DecodeResponse\x12H\n" +
+	"\x05Count\x12\x1e.bpe.tokenizer.v1.CountRequest\x1a\x1f.bpe.tokenizer.v1.CountResponse\x12U\n" +
+	"\fEncodeStream\x12\x1f.bpe.tokenizer.v1.EncodeRequest\x1a .bpe.tokenizer.v1.EncodeResponse(\x010\x01\x12U\n" +
+	"\fDecodeStream\x12\x1f.bpe.tokenizer.v1.DecodeRequest\x1a .bpe.tokenizer.v1.DecodeResponse(\x010\x01B#Z!bpicori/bpe-tokenizer/tokenizerpbb\x06proto3"
+
+var (
+	file_tokenizerpb_tokenizer_proto_rawDescOnce sync.Once
+	file_tokenizerpb_tokenizer_proto_rawDescData []byte
+)
+
+func file_tokenizerpb_tokenizer_proto_rawDescGZIP() []byte {
+	file_tokenizerpb_tokenizer_proto_rawDescOnce.Do(func() {
+		file_tokenizerpb_tokenizer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_tokenizerpb_tokenizer_proto_rawDesc), len(file_tokenizerpb_tokenizer_proto_rawDesc)))
+	})
+	return file_tokenizerpb_tokenizer_proto_rawDescData
+}
+
+var file_tokenizerpb_tokenizer_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_tokenizerpb_tokenizer_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_tokenizerpb_tokenizer_proto_goTypes = []any{
+	(UTF8Policy)(0),        // 0: bpe.tokenizer.v1.UTF8Policy
+	(*EncodeRequest)(nil),  // 1: bpe.tokenizer.v1.EncodeRequest
+	(*EncodeResponse)(nil), // 2: bpe.tokenizer.v1.EncodeResponse
+	(*DecodeRequest)(nil),  // 3: bpe.tokenizer.v1.DecodeRequest
+	(*DecodeResponse)(nil), // 4: bpe.tokenizer.v1.DecodeResponse
+	(*CountRequest)(nil),   // 5: bpe.tokenizer.v1.CountRequest
+	(*CountResponse)(nil),  // 6: bpe.tokenizer.v1.CountResponse
+}
+var file_tokenizerpb_tokenizer_proto_depIdxs = []int32{
+	0, // 0: bpe.tokenizer.v1.DecodeRequest.utf8:type_name -> bpe.tokenizer.v1.UTF8Policy
+	1, // 1: bpe.tokenizer.v1.Tokenizer.Encode:input_type -> bpe.tokenizer.v1.EncodeRequest
+	3, // 2: bpe.tokenizer.v1.Tokenizer.Decode:input_type -> bpe.tokenizer.v1.DecodeRequest
+	5, // 3: bpe.tokenizer.v1.Tokenizer.Count:input_type -> bpe.tokenizer.v1.CountRequest
+	1, // 4: bpe.tokenizer.v1.Tokenizer.EncodeStream:input_type -> bpe.tokenizer.v1.EncodeRequest
+	3, // 5: bpe.tokenizer.v1.Tokenizer.DecodeStream:input_type -> bpe.tokenizer.v1.DecodeRequest
+	2, // 6: bpe.tokenizer.v1.Tokenizer.Encode:output_type -> bpe.tokenizer.v1.EncodeResponse
+	4, // 7: bpe.tokenizer.v1.Tokenizer.Decode:output_type -> bpe.tokenizer.v1.DecodeResponse
+	6, // 8: bpe.tokenizer.v1.Tokenizer.Count:output_type -> bpe.tokenizer.v1.CountResponse
+	2, // 9: bpe.tokenizer.v1.Tokenizer.EncodeStream:output_type -> bpe.tokenizer.v1.EncodeResponse
+	4, // 10: bpe.tokenizer.v1.Tokenizer.DecodeStream:output_type -> bpe.tokenizer.v1.DecodeResponse
+	6, // [6:11] is the sub-list for method output_type
+	1, // [1:6] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_tokenizerpb_tokenizer_proto_init() }
+func file_tokenizerpb_tokenizer_proto_init() {
+	if File_tokenizerpb_tokenizer_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tokenizerpb_tokenizer_proto_rawDesc), len(file_tokenizerpb_tokenizer_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_tokenizerpb_tokenizer_proto_goTypes,
+		DependencyIndexes: file_tokenizerpb_tokenizer_proto_depIdxs,
+		EnumInfos:         file_tokenizerpb_tokenizer_proto_enumTypes,
+		MessageInfos:      file_tokenizerpb_tokenizer_proto_msgTypes,
+	}.Build()
+	File_tokenizerpb_tokenizer_proto = out.File
+	file_tokenizerpb_tokenizer_proto_goTypes = nil
+	file_tokenizerpb_tokenizer_proto_depIdxs = nil
+}
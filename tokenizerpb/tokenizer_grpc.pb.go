@@ -0,0 +1,278 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: tokenizerpb/tokenizer.proto
+
+package tokenizerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Tokenizer_Encode_FullMethodName       = "/bpe.tokenizer.v1.Tokenizer/Encode"
+	Tokenizer_Decode_FullMethodName       = "/bpe.tokenizer.v1.Tokenizer/Decode"
+	Tokenizer_Count_FullMethodName        = "/bpe.tokenizer.v1.Tokenizer/Count"
+	Tokenizer_EncodeStream_FullMethodName = "/bpe.tokenizer.v1.Tokenizer/EncodeStream"
+	Tokenizer_DecodeStream_FullMethodName = "/bpe.tokenizer.v1.Tokenizer/DecodeStream"
+)
+
+// TokenizerClient is the client API for Tokenizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Tokenizer encodes and decodes text with named BPE models.
+type TokenizerClient interface {
+	Encode(ctx context.Context, in *EncodeRequest, opts ...grpc.CallOption) (*EncodeResponse, error)
+	Decode(ctx context.Context, in *DecodeRequest, opts ...grpc.CallOption) (*DecodeResponse, error)
+	Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	// EncodeStream answers every text it receives with its ids, in order.
+	EncodeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[EncodeRequest, EncodeResponse], error)
+	// DecodeStream receives ids as they are generated and answers each message
+	// with the text completed so far. Bytes of a character split across ids are
+	// held back until the character is complete, and flushed when the client
+	// closes its side of the stream. The model, strictness and UTF-8 policy of
+	// the first message apply to the whole stream.
+	DecodeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[DecodeRequest, DecodeResponse], error)
+}
+
+type tokenizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenizerClient(cc grpc.ClientConnInterface) TokenizerClient {
+	return &tokenizerClient{cc}
+}
+
+func (c *tokenizerClient) Encode(ctx context.Context, in *EncodeRequest, opts ...grpc.CallOption) (*EncodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EncodeResponse)
+	err := c.cc.Invoke(ctx, Tokenizer_Encode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenizerClient) Decode(ctx context.Context, in *DecodeRequest, opts ...grpc.CallOption) (*DecodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DecodeResponse)
+	err := c.cc.Invoke(ctx, Tokenizer_Decode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenizerClient) Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, Tokenizer_Count_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenizerClient) EncodeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[EncodeRequest, EncodeResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Tokenizer_ServiceDesc.Streams[0], Tokenizer_EncodeStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[EncodeRequest, EncodeResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tokenizer_EncodeStreamClient = grpc.BidiStreamingClient[EncodeRequest, EncodeResponse]
+
+func (c *tokenizerClient) DecodeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[DecodeRequest, DecodeResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Tokenizer_ServiceDesc.Streams[1], Tokenizer_DecodeStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DecodeRequest, DecodeResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tokenizer_DecodeStreamClient = grpc.BidiStreamingClient[DecodeRequest, DecodeResponse]
+
+// TokenizerServer is the server API for Tokenizer service.
+// All implementations must embed UnimplementedTokenizerServer
+// for forward compatibility.
+//
+// Tokenizer encodes and decodes text with named BPE models.
+type TokenizerServer interface {
+	Encode(context.Context, *EncodeRequest) (*EncodeResponse, error)
+	Decode(context.Context, *DecodeRequest) (*DecodeResponse, error)
+	Count(context.Context, *CountRequest) (*CountResponse, error)
+	// EncodeStream answers every text it receives with its ids, in order.
+	EncodeStream(grpc.BidiStreamingServer[EncodeRequest, EncodeResponse]) error
+	// DecodeStream receives ids as they are generated and answers each message
+	// with the text completed so far. Bytes of a character split across ids are
+	// held back until the character is complete, and flushed when the client
+	// closes its side of the stream. The model, strictness and UTF-8 policy of
+	// the first message apply to the whole stream.
+	DecodeStream(grpc.BidiStreamingServer[DecodeRequest, DecodeResponse]) error
+	mustEmbedUnimplementedTokenizerServer()
+}
+
+// UnimplementedTokenizerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTokenizerServer struct{}
+
+func (UnimplementedTokenizerServer) Encode(context.Context, *EncodeRequest) (*EncodeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Encode not implemented")
+}
+func (UnimplementedTokenizerServer) Decode(context.Context, *DecodeRequest) (*DecodeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Decode not implemented")
+}
+func (UnimplementedTokenizerServer) Count(context.Context, *CountRequest) (*CountResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Count not implemented")
+}
+func (UnimplementedTokenizerServer) EncodeStream(grpc.BidiStreamingServer[EncodeRequest, EncodeResponse]) error {
+	return status.Error(codes.Unimplemented, "method EncodeStream not implemented")
+}
+func (UnimplementedTokenizerServer) DecodeStream(grpc.BidiStreamingServer[DecodeRequest, DecodeResponse]) error {
+	return status.Error(codes.Unimplemented, "method DecodeStream not implemented")
+}
+func (UnimplementedTokenizerServer) mustEmbedUnimplementedTokenizerServer() {}
+func (UnimplementedTokenizerServer) testEmbeddedByValue()                   {}
+
+// UnsafeTokenizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenizerServer will
+// result in compilation errors.
+type UnsafeTokenizerServer interface {
+	mustEmbedUnimplementedTokenizerServer()
+}
+
+func RegisterTokenizerServer(s grpc.ServiceRegistrar, srv TokenizerServer) {
+	// If the following call panics, it indicates UnimplementedTokenizerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Tokenizer_ServiceDesc, srv)
+}
+
+func _Tokenizer_Encode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EncodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenizerServer).Encode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tokenizer_Encode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenizerServer).Encode(ctx, req.(*EncodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tokenizer_Decode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenizerServer).Decode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tokenizer_Decode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenizerServer).Decode(ctx, req.(*DecodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tokenizer_Count_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenizerServer).Count(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tokenizer_Count_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenizerServer).Count(ctx, req.(*CountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tokenizer_EncodeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TokenizerServer).EncodeStream(&grpc.GenericServerStream[EncodeRequest, EncodeResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tokenizer_EncodeStreamServer = grpc.BidiStreamingServer[EncodeRequest, EncodeResponse]
+
+func _Tokenizer_DecodeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TokenizerServer).DecodeStream(&grpc.GenericServerStream[DecodeRequest, DecodeResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tokenizer_DecodeStreamServer = grpc.BidiStreamingServer[DecodeRequest, DecodeResponse]
+
+// Tokenizer_ServiceDesc is the grpc.ServiceDesc for Tokenizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Tokenizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bpe.tokenizer.v1.Tokenizer",
+	HandlerType: (*TokenizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Encode",
+			Handler:    _Tokenizer_Encode_Handler,
+		},
+		{
+			MethodName: "Decode",
+			Handler:    _Tokenizer_Decode_Handler,
+		},
+		{
+			MethodName: "Count",
+			Handler:    _Tokenizer_Count_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "EncodeStream",
+			Handler:       _Tokenizer_EncodeStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DecodeStream",
+			Handler:       _Tokenizer_DecodeStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "tokenizerpb/tokenizer.proto",
+}